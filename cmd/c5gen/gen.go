@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// runtimePath is the package of the helpers the generated code calls
+const runtimePath = "github.com/mabels/c5-envelope/pkg"
+
+type generator struct {
+	model *model
+	pkg   string
+	// rt qualifies the helpers, empty within the c5 package itself
+	rt      string
+	out     bytes.Buffer
+	imports map[string]bool
+}
+
+// generate renders the Go source of the model in package pkg, sources
+// name the schema files for the header.
+func generate(m *model, pkg string, sources []string) ([]byte, error) {
+	g := &generator{model: m, pkg: pkg, imports: map[string]bool{"encoding/json": true}}
+	if pkg != "c5" {
+		g.rt = "c5."
+		g.imports[runtimePath] = true
+	}
+	names := make([]string, 0, len(m.decls))
+	for name := range m.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d := m.decls[name]
+		switch d.kind {
+		case declStruct:
+			g.structDecl(d)
+		case declEnum:
+			g.enumDecl(d)
+		case declMap:
+			g.mapDecl(d)
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by c5gen from %v. DO NOT EDIT.\n\n", strings.Join(sources, ", "))
+	fmt.Fprintf(&src, "package %v\n\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	src.WriteString("import (\n")
+	for _, path := range imports {
+		if path != runtimePath {
+			fmt.Fprintf(&src, "\t%q\n", path)
+		}
+	}
+	if g.imports[runtimePath] {
+		fmt.Fprintf(&src, "\n\tc5 %q\n", runtimePath)
+	}
+	src.WriteString(")\n")
+	src.Write(g.out.Bytes())
+	return format.Source(src.Bytes())
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.out, format, args...)
+	g.out.WriteByte('\n')
+}
+
+func (g *generator) doc(name string, doc string) {
+	if doc == "" {
+		return
+	}
+	for idx, line := range strings.Split(doc, "\n") {
+		if idx == 0 && name != "" {
+			line = name + " " + line
+		}
+		g.p("// %v", line)
+	}
+}
+
+// typeName is the Go type of t in the struct
+func (g *generator) typeName(t *goType) string {
+	switch t.kind {
+	case kindString:
+		return "string"
+	case kindNumber:
+		return "float64"
+	case kindInteger:
+		return "int64"
+	case kindBoolean:
+		return "bool"
+	case kindArray:
+		return "[]" + g.typeName(t.elem)
+	case kindMap:
+		return "map[string]" + g.typeName(t.elem)
+	case kindRef:
+		return t.ref
+	}
+	return "interface{}"
+}
+
+// dictName is the Go type of t in the ToDict result
+func (g *generator) dictName(t *goType) string {
+	switch t.kind {
+	case kindArray:
+		return "[]" + g.dictName(t.elem)
+	case kindMap:
+		return "map[string]interface{}"
+	case kindRef:
+		switch g.model.decls[t.ref].kind {
+		case declEnum:
+			return "string"
+		case declStruct, declMap:
+			return "map[string]interface{}"
+		}
+	}
+	return g.typeName(t)
+}
+
+// pointer tells if an optional field of type t is a pointer, slices and
+// maps have nil for absent.
+func (g *generator) pointer(t *goType) bool {
+	switch t.kind {
+	case kindAny, kindArray, kindMap:
+		return false
+	case kindRef:
+		return g.model.decls[t.ref].kind != declMap
+	}
+	return true
+}
+
+func (g *generator) fieldType(f field) string {
+	if !f.required && g.pointer(f.typ) {
+		return "*" + g.typeName(f.typ)
+	}
+	return g.typeName(f.typ)
+}
+
+func (g *generator) structDecl(d *decl) {
+	rt := g.rt
+	g.p("")
+	g.doc(d.name, d.doc)
+	g.p("type %v struct {", d.name)
+	for _, f := range d.fields {
+		tag := f.json
+		if !f.required {
+			tag += ",omitempty"
+		}
+		g.doc("", f.doc)
+		g.p("%v %v `json:%q`", f.name, g.fieldType(f), tag)
+	}
+	g.p("}")
+	g.p("")
+	g.p("func (r *%v) Marshal() ([]byte, error) {", d.name)
+	g.p("return json.Marshal(r)")
+	g.p("}")
+	g.p("")
+	g.p("func Unmarshal%v(data []byte, opts ...%vFromDictOption) (*%v, error) {", d.name, rt, d.name)
+	g.p("dict, err := %vDecodeDict(data)", rt)
+	g.p("if err != nil {")
+	g.p("return nil, err")
+	g.p("}")
+	g.p("ins := %v{}", d.name)
+	g.p("return &ins, FromDict%v(dict, &ins, opts...)", d.name)
+	g.p("}")
+	g.p("")
+	g.p("func (r *%v) ToDict() map[string]interface{} {", d.name)
+	g.p("dict := map[string]interface{}{}")
+	for _, f := range d.fields {
+		target := fmt.Sprintf("dict[%q]", f.json)
+		src := "r." + f.name
+		if f.required {
+			g.encode(f.typ, src, target, 0, false)
+			continue
+		}
+		g.p("if %v != nil {", src)
+		if g.pointer(f.typ) && !g.isStruct(f.typ) {
+			src = "*" + src
+		}
+		g.encode(f.typ, src, target, 0, true)
+		g.p("}")
+	}
+	g.p("return dict")
+	g.p("}")
+	g.p("")
+	g.p("func FromDict%v(data map[string]interface{}, r *%v, opts ...%vFromDictOption) error {", d.name, d.name, rt)
+	g.p("return fromDict%v(data, r, \"\", %vNewFromDictOptions(opts))", d.name, rt)
+	g.p("}")
+	g.p("")
+	g.p("func fromDict%v(data map[string]interface{}, r *%v, path string, o *%vFromDictOptions) error {", d.name, d.name, rt)
+	known := make([]string, len(d.fields))
+	for idx, f := range d.fields {
+		known[idx] = strconv.Quote(f.json)
+	}
+	g.p("if err := %vDictKnown(data, path, o, %v); err != nil {", rt, strings.Join(known, ", "))
+	g.p("return err")
+	g.p("}")
+	for _, f := range d.fields {
+		path := fmt.Sprintf("path + %q", "/"+escapePointer(f.json))
+		if f.required {
+			g.p("{")
+			g.p("v, err := %vDictField(data, %q, path)", rt, f.json)
+			g.p("if err != nil {")
+			g.p("return err")
+			g.p("}")
+			g.decode(f.typ, "v", "r."+f.name, path, 0)
+			g.p("}")
+			continue
+		}
+		g.p("if v, found := data[%q]; found && v != nil {", f.json)
+		if g.pointer(f.typ) {
+			g.p("var item %v", g.typeName(f.typ))
+			g.decode(f.typ, "v", "item", path, 0)
+			g.p("r.%v = &item", f.name)
+		} else {
+			g.decode(f.typ, "v", "r."+f.name, path, 0)
+		}
+		g.p("}")
+	}
+	g.p("return nil")
+	g.p("}")
+}
+
+func (g *generator) isStruct(t *goType) bool {
+	return t.kind == kindRef && g.model.decls[t.ref].kind == declStruct
+}
+
+// encode emits the statements which store src into target in the
+// shape of the ToDict result, scoped tells if they have a block of
+// their own.
+func (g *generator) encode(t *goType, src string, target string, depth int, scoped bool) {
+	switch t.kind {
+	case kindArray:
+		tmp, idx, i := vars(depth, "tmp", "idx", "i")
+		if !scoped {
+			g.p("{")
+		}
+		g.p("%v := make(%v, len(%v))", tmp, g.dictName(t), src)
+		g.p("for %v, %v := range %v {", idx, i, src)
+		g.encode(t.elem, i, tmp+"["+idx+"]", depth+1, true)
+		g.p("}")
+		g.p("%v = %v", target, tmp)
+		if !scoped {
+			g.p("}")
+		}
+		return
+	case kindMap:
+		g.encodeMap(t.elem, src, target, depth, scoped)
+		return
+	case kindRef:
+		d := g.model.decls[t.ref]
+		switch d.kind {
+		case declStruct:
+			g.p("%v = %v.ToDict()", target, src)
+		case declEnum:
+			g.p("%v = To%v(%v)", target, d.name, src)
+		case declMap:
+			g.encodeMap(d.elem, src, target, depth, scoped)
+		}
+		return
+	}
+	g.p("%v = %v", target, src)
+}
+
+func (g *generator) encodeMap(elem *goType, src string, target string, depth int, scoped bool) {
+	tmp, key, i := vars(depth, "tmp", "key", "i")
+	if !scoped {
+		g.p("{")
+	}
+	g.p("%v := make(map[string]interface{}, len(%v))", tmp, src)
+	g.p("for %v, %v := range %v {", key, i, src)
+	g.encode(elem, i, tmp+"["+key+"]", depth+1, true)
+	g.p("}")
+	g.p("%v = %v", target, tmp)
+	if !scoped {
+		g.p("}")
+	}
+}
+
+// decode emits the statements which check val, found at the JSON
+// pointer the expression path evaluates to, and store it into target.
+func (g *generator) decode(t *goType, val string, target string, path string, depth int) {
+	rt := g.rt
+	scalar := func(helper string) {
+		g.p("x, err := %v%v(%v, %v)", rt, helper, val, path)
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("%v = x", target)
+	}
+	switch t.kind {
+	case kindString:
+		scalar("DictString")
+	case kindNumber:
+		scalar("DictNumber")
+	case kindInteger:
+		scalar("DictInteger")
+	case kindBoolean:
+		scalar("DictBoolean")
+	case kindArray:
+		idx, i, _ := vars(depth, "idx", "i")
+		g.imports["strconv"] = true
+		g.p("arr, err := %vDictArray(%v, %v)", rt, val, path)
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.p("%v = make(%v, len(arr))", target, g.typeName(t))
+		g.p("for %v, %v := range arr {", idx, i)
+		g.decode(t.elem, i, target+"["+idx+"]", path+` + "/" + strconv.Itoa(`+idx+`)`, depth+1)
+		g.p("}")
+	case kindMap:
+		g.decodeMap(t.elem, g.typeName(t), val, target, path, depth)
+	case kindRef:
+		d := g.model.decls[t.ref]
+		switch d.kind {
+		case declStruct:
+			g.p("obj, err := %vDictObject(%v, %v)", rt, val, path)
+			g.p("if err != nil {")
+			g.p("return err")
+			g.p("}")
+			g.p("if err := fromDict%v(obj, &%v, %v, o); err != nil {", d.name, target, path)
+			g.p("return err")
+			g.p("}")
+		case declEnum:
+			g.p("s, err := %vDictString(%v, %v)", rt, val, path)
+			g.p("if err != nil {")
+			g.p("return err")
+			g.p("}")
+			g.p("e, err := From%v(s)", d.name)
+			g.p("if err != nil {")
+			g.p("return &%vFieldError{Path: %v, Err: err}", rt, path)
+			g.p("}")
+			g.p("%v = e", target)
+		case declMap:
+			g.decodeMap(d.elem, d.name, val, target, path, depth)
+		}
+	default:
+		g.p("%v = %v", target, val)
+	}
+}
+
+func (g *generator) decodeMap(elem *goType, typeName string, val string, target string, path string, depth int) {
+	key, i, item := vars(depth, "key", "i", "item")
+	g.p("obj, err := %vDictObject(%v, %v)", g.rt, val, path)
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("%v = make(%v, len(obj))", target, typeName)
+	g.p("for %v, %v := range obj {", key, i)
+	if elem.kind == kindAny {
+		g.p("%v[%v] = %v", target, key, i)
+	} else {
+		g.p("var %v %v", item, g.typeName(elem))
+		g.decode(elem, i, item, path+` + "/" + `+key, depth+1)
+		g.p("%v[%v] = %v", target, key, item)
+	}
+	g.p("}")
+}
+
+// vars names the variables of a nesting level
+func vars(depth int, names ...string) (string, string, string) {
+	out := make([]string, 3)
+	for idx, name := range names {
+		if depth > 0 {
+			name += strconv.Itoa(depth)
+		}
+		out[idx] = name
+	}
+	return out[0], out[1], out[2]
+}
+
+func (g *generator) enumDecl(d *decl) {
+	g.imports["fmt"] = true
+	g.p("")
+	g.doc(d.name, d.doc)
+	g.p("type %v string", d.name)
+	g.p("")
+	g.p("const (")
+	for _, v := range d.values {
+		g.p("%v_%v %v = %q", d.name, goName(v), d.name, v)
+	}
+	g.p(")")
+	g.p("")
+	g.p("func From%v(v string) (%v, error) {", d.name, d.name)
+	g.p("switch v {")
+	for _, v := range d.values {
+		g.p("case %q:", v)
+		g.p("return %v_%v, nil", d.name, goName(v))
+	}
+	g.p("}")
+	g.p("return \"\", fmt.Errorf(\"enum %v has no value '%%v'\", v)", d.name)
+	g.p("}")
+	g.p("")
+	g.p("func To%v(v %v) string {", d.name, d.name)
+	g.p("return string(v)")
+	g.p("}")
+}
+
+func (g *generator) mapDecl(d *decl) {
+	g.p("")
+	g.doc(d.name, d.doc)
+	g.p("type %v map[string]%v", d.name, g.typeName(d.elem))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type GenSuite struct {
+	suite.Suite
+}
+
+// TestUpToDate checks the generated files against their schemas, run
+// go generate ./... if it fails
+func (s *GenSuite) TestUpToDate() {
+	for _, tc := range []struct {
+		pkg    string
+		schema string
+		out    string
+	}{
+		{"c5", "../../schema/envelope.json", "../../pkg/envelope.go"},
+		{"example", "testdata/order.json", "internal/example/order.go"},
+	} {
+		src, err := run(tc.pkg, []string{tc.schema})
+		assert.NoError(s.T(), err)
+		expected, err := os.ReadFile(tc.out)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), string(expected), string(src), tc.out)
+	}
+}
+
+func (s *GenSuite) TestGoName() {
+	for in, out := range map[string]string{
+		"id":         "ID",
+		"ttl":        "TTL",
+		"src":        "Src",
+		"PayloadT1":  "PayloadT1",
+		"in-transit": "InTransit",
+		"userURL":    "UserURL",
+		"HTTPServer": "HTTPServer",
+		"snake_case": "SnakeCase",
+		"1st":        "X1st",
+		"":           "X",
+	} {
+		assert.Equal(s.T(), out, goName(in), in)
+	}
+}
+
+func (s *GenSuite) TestSchemaErrors() {
+	for _, tc := range []struct {
+		doc     string
+		pointer string
+	}{
+		{`[]`, ""},
+		{`{"properties": {}}`, ""},
+		{`{"$defs": {"a": {"type": "string"}}}`, "/$defs/a"},
+		{`{"$defs": {"a": {"type": "object", "properties": {"b": {"type": "null"}}}}}`, "/$defs/a/properties/b/type"},
+		{`{"$defs": {"a": {"type": "object", "properties": {"b": {"oneOf": []}}}}}`, "/$defs/a/properties/b/oneOf"},
+		{`{"$defs": {"a": {"type": "object", "properties": {"b": {"$ref": "other.json"}}}}}`, "/$defs/a/properties/b/$ref"},
+		{`{"$defs": {"a": {"type": "object", "properties": {"b": false}}}}`, "/$defs/a/properties/b"},
+		{`{"$defs": {"a": {"enum": ["x", 1]}}}`, "/$defs/a/enum/1"},
+		{`{"$defs": {"a": {"enum": ["a-b", "a_b"]}}}`, "/$defs/a/enum"},
+		{`{"$defs": {"a": {"type": "object", "properties": {"b-c": {}, "b_c": {}}}}}`, "/$defs/a/properties"},
+		{`{"$defs": {"a": {"type": "object"}}, "definitions": {"A": {"type": "object"}}}`, "/definitions/A"},
+	} {
+		err := newModel().addSchema("test.json", []byte(tc.doc))
+		var serr *SchemaError
+		if assert.True(s.T(), errors.As(err, &serr), tc.doc) {
+			assert.Equal(s.T(), tc.pointer, serr.Pointer, tc.doc)
+		}
+	}
+}
+
+func (s *GenSuite) TestUndefinedRef() {
+	m := newModel()
+	assert.NoError(s.T(), m.addSchema("test.json", []byte(`{"$defs": {"a": {"type": "object", "properties": {"b": {"$ref": "#/$defs/c"}}}}}`)))
+	assert.EqualError(s.T(), m.resolve(), "#/$defs/a: 'C' is not defined")
+}
+
+func TestGenSuite(t *testing.T) {
+	suite.Run(t, new(GenSuite))
+}
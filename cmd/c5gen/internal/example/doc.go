@@ -0,0 +1,5 @@
+// Package example is generated from testdata/order.json to check that the
+// code of c5gen builds outside of the c5 package.
+package example
+
+//go:generate go run ../.. -package example -o order.go ../../testdata/order.json
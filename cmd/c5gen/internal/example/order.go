@@ -0,0 +1,399 @@
+// Code generated by c5gen from order.json. DO NOT EDIT.
+
+package example
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+type Attributes map[string]float64
+
+// Item is a line of an order
+type Item struct {
+	Attributes Attributes `json:"attributes,omitempty"`
+	Count      int64      `json:"count"`
+	Sku        string     `json:"sku"`
+}
+
+func (r *Item) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalItem(data []byte, opts ...c5.FromDictOption) (*Item, error) {
+	dict, err := c5.DecodeDict(data)
+	if err != nil {
+		return nil, err
+	}
+	ins := Item{}
+	return &ins, FromDictItem(dict, &ins, opts...)
+}
+
+func (r *Item) ToDict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	if r.Attributes != nil {
+		tmp := make(map[string]interface{}, len(r.Attributes))
+		for key, i := range r.Attributes {
+			tmp[key] = i
+		}
+		dict["attributes"] = tmp
+	}
+	dict["count"] = r.Count
+	dict["sku"] = r.Sku
+	return dict
+}
+
+func FromDictItem(data map[string]interface{}, r *Item, opts ...c5.FromDictOption) error {
+	return fromDictItem(data, r, "", c5.NewFromDictOptions(opts))
+}
+
+func fromDictItem(data map[string]interface{}, r *Item, path string, o *c5.FromDictOptions) error {
+	if err := c5.DictKnown(data, path, o, "attributes", "count", "sku"); err != nil {
+		return err
+	}
+	if v, found := data["attributes"]; found && v != nil {
+		obj, err := c5.DictObject(v, path+"/attributes")
+		if err != nil {
+			return err
+		}
+		r.Attributes = make(Attributes, len(obj))
+		for key, i := range obj {
+			var item float64
+			x, err := c5.DictNumber(i, path+"/attributes"+"/"+key)
+			if err != nil {
+				return err
+			}
+			item = x
+			r.Attributes[key] = item
+		}
+	}
+	{
+		v, err := c5.DictField(data, "count", path)
+		if err != nil {
+			return err
+		}
+		x, err := c5.DictInteger(v, path+"/count")
+		if err != nil {
+			return err
+		}
+		r.Count = x
+	}
+	{
+		v, err := c5.DictField(data, "sku", path)
+		if err != nil {
+			return err
+		}
+		x, err := c5.DictString(v, path+"/sku")
+		if err != nil {
+			return err
+		}
+		r.Sku = x
+	}
+	return nil
+}
+
+type Order struct {
+	Customer string            `json:"customer"`
+	Discount *float64          `json:"discount,omitempty"`
+	Extra    interface{}       `json:"extra,omitempty"`
+	Grid     [][]int64         `json:"grid,omitempty"`
+	ID       int64             `json:"id"`
+	Items    []Item            `json:"items"`
+	Note     *string           `json:"note,omitempty"`
+	Paid     bool              `json:"paid"`
+	Shipping *OrderShipping    `json:"shipping,omitempty"`
+	Status   *OrderStatus      `json:"status,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+func (r *Order) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalOrder(data []byte, opts ...c5.FromDictOption) (*Order, error) {
+	dict, err := c5.DecodeDict(data)
+	if err != nil {
+		return nil, err
+	}
+	ins := Order{}
+	return &ins, FromDictOrder(dict, &ins, opts...)
+}
+
+func (r *Order) ToDict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	dict["customer"] = r.Customer
+	if r.Discount != nil {
+		dict["discount"] = *r.Discount
+	}
+	if r.Extra != nil {
+		dict["extra"] = r.Extra
+	}
+	if r.Grid != nil {
+		tmp := make([][]int64, len(r.Grid))
+		for idx, i := range r.Grid {
+			tmp1 := make([]int64, len(i))
+			for idx1, i1 := range i {
+				tmp1[idx1] = i1
+			}
+			tmp[idx] = tmp1
+		}
+		dict["grid"] = tmp
+	}
+	dict["id"] = r.ID
+	{
+		tmp := make([]map[string]interface{}, len(r.Items))
+		for idx, i := range r.Items {
+			tmp[idx] = i.ToDict()
+		}
+		dict["items"] = tmp
+	}
+	if r.Note != nil {
+		dict["note"] = *r.Note
+	}
+	dict["paid"] = r.Paid
+	if r.Shipping != nil {
+		dict["shipping"] = r.Shipping.ToDict()
+	}
+	if r.Status != nil {
+		dict["status"] = ToOrderStatus(*r.Status)
+	}
+	if r.Tags != nil {
+		tmp := make(map[string]interface{}, len(r.Tags))
+		for key, i := range r.Tags {
+			tmp[key] = i
+		}
+		dict["tags"] = tmp
+	}
+	return dict
+}
+
+func FromDictOrder(data map[string]interface{}, r *Order, opts ...c5.FromDictOption) error {
+	return fromDictOrder(data, r, "", c5.NewFromDictOptions(opts))
+}
+
+func fromDictOrder(data map[string]interface{}, r *Order, path string, o *c5.FromDictOptions) error {
+	if err := c5.DictKnown(data, path, o, "customer", "discount", "extra", "grid", "id", "items", "note", "paid", "shipping", "status", "tags"); err != nil {
+		return err
+	}
+	{
+		v, err := c5.DictField(data, "customer", path)
+		if err != nil {
+			return err
+		}
+		x, err := c5.DictString(v, path+"/customer")
+		if err != nil {
+			return err
+		}
+		r.Customer = x
+	}
+	if v, found := data["discount"]; found && v != nil {
+		var item float64
+		x, err := c5.DictNumber(v, path+"/discount")
+		if err != nil {
+			return err
+		}
+		item = x
+		r.Discount = &item
+	}
+	if v, found := data["extra"]; found && v != nil {
+		r.Extra = v
+	}
+	if v, found := data["grid"]; found && v != nil {
+		arr, err := c5.DictArray(v, path+"/grid")
+		if err != nil {
+			return err
+		}
+		r.Grid = make([][]int64, len(arr))
+		for idx, i := range arr {
+			arr, err := c5.DictArray(i, path+"/grid"+"/"+strconv.Itoa(idx))
+			if err != nil {
+				return err
+			}
+			r.Grid[idx] = make([]int64, len(arr))
+			for idx1, i1 := range arr {
+				x, err := c5.DictInteger(i1, path+"/grid"+"/"+strconv.Itoa(idx)+"/"+strconv.Itoa(idx1))
+				if err != nil {
+					return err
+				}
+				r.Grid[idx][idx1] = x
+			}
+		}
+	}
+	{
+		v, err := c5.DictField(data, "id", path)
+		if err != nil {
+			return err
+		}
+		x, err := c5.DictInteger(v, path+"/id")
+		if err != nil {
+			return err
+		}
+		r.ID = x
+	}
+	{
+		v, err := c5.DictField(data, "items", path)
+		if err != nil {
+			return err
+		}
+		arr, err := c5.DictArray(v, path+"/items")
+		if err != nil {
+			return err
+		}
+		r.Items = make([]Item, len(arr))
+		for idx, i := range arr {
+			obj, err := c5.DictObject(i, path+"/items"+"/"+strconv.Itoa(idx))
+			if err != nil {
+				return err
+			}
+			if err := fromDictItem(obj, &r.Items[idx], path+"/items"+"/"+strconv.Itoa(idx), o); err != nil {
+				return err
+			}
+		}
+	}
+	if v, found := data["note"]; found && v != nil {
+		var item string
+		x, err := c5.DictString(v, path+"/note")
+		if err != nil {
+			return err
+		}
+		item = x
+		r.Note = &item
+	}
+	{
+		v, err := c5.DictField(data, "paid", path)
+		if err != nil {
+			return err
+		}
+		x, err := c5.DictBoolean(v, path+"/paid")
+		if err != nil {
+			return err
+		}
+		r.Paid = x
+	}
+	if v, found := data["shipping"]; found && v != nil {
+		var item OrderShipping
+		obj, err := c5.DictObject(v, path+"/shipping")
+		if err != nil {
+			return err
+		}
+		if err := fromDictOrderShipping(obj, &item, path+"/shipping", o); err != nil {
+			return err
+		}
+		r.Shipping = &item
+	}
+	if v, found := data["status"]; found && v != nil {
+		var item OrderStatus
+		s, err := c5.DictString(v, path+"/status")
+		if err != nil {
+			return err
+		}
+		e, err := FromOrderStatus(s)
+		if err != nil {
+			return &c5.FieldError{Path: path + "/status", Err: err}
+		}
+		item = e
+		r.Status = &item
+	}
+	if v, found := data["tags"]; found && v != nil {
+		obj, err := c5.DictObject(v, path+"/tags")
+		if err != nil {
+			return err
+		}
+		r.Tags = make(map[string]string, len(obj))
+		for key, i := range obj {
+			var item string
+			x, err := c5.DictString(i, path+"/tags"+"/"+key)
+			if err != nil {
+				return err
+			}
+			item = x
+			r.Tags[key] = item
+		}
+	}
+	return nil
+}
+
+type OrderShipping struct {
+	City string  `json:"city"`
+	Zip  *string `json:"zip,omitempty"`
+}
+
+func (r *OrderShipping) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalOrderShipping(data []byte, opts ...c5.FromDictOption) (*OrderShipping, error) {
+	dict, err := c5.DecodeDict(data)
+	if err != nil {
+		return nil, err
+	}
+	ins := OrderShipping{}
+	return &ins, FromDictOrderShipping(dict, &ins, opts...)
+}
+
+func (r *OrderShipping) ToDict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	dict["city"] = r.City
+	if r.Zip != nil {
+		dict["zip"] = *r.Zip
+	}
+	return dict
+}
+
+func FromDictOrderShipping(data map[string]interface{}, r *OrderShipping, opts ...c5.FromDictOption) error {
+	return fromDictOrderShipping(data, r, "", c5.NewFromDictOptions(opts))
+}
+
+func fromDictOrderShipping(data map[string]interface{}, r *OrderShipping, path string, o *c5.FromDictOptions) error {
+	if err := c5.DictKnown(data, path, o, "city", "zip"); err != nil {
+		return err
+	}
+	{
+		v, err := c5.DictField(data, "city", path)
+		if err != nil {
+			return err
+		}
+		x, err := c5.DictString(v, path+"/city")
+		if err != nil {
+			return err
+		}
+		r.City = x
+	}
+	if v, found := data["zip"]; found && v != nil {
+		var item string
+		x, err := c5.DictString(v, path+"/zip")
+		if err != nil {
+			return err
+		}
+		item = x
+		r.Zip = &item
+	}
+	return nil
+}
+
+type OrderStatus string
+
+const (
+	OrderStatus_New       OrderStatus = "new"
+	OrderStatus_InTransit OrderStatus = "in-transit"
+	OrderStatus_Done      OrderStatus = "done"
+)
+
+func FromOrderStatus(v string) (OrderStatus, error) {
+	switch v {
+	case "new":
+		return OrderStatus_New, nil
+	case "in-transit":
+		return OrderStatus_InTransit, nil
+	case "done":
+		return OrderStatus_Done, nil
+	}
+	return "", fmt.Errorf("enum OrderStatus has no value '%v'", v)
+}
+
+func ToOrderStatus(v OrderStatus) string {
+	return string(v)
+}
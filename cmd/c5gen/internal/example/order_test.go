@@ -0,0 +1,102 @@
+package example
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OrderSuite struct {
+	suite.Suite
+}
+
+const orderJson = `{
+	"id": 7,
+	"customer": "c1",
+	"paid": true,
+	"status": "in-transit",
+	"items": [{"sku": "a", "count": 2, "attributes": {"weight": 1.5}}],
+	"tags": {"gift": "yes"},
+	"shipping": {"city": "Berlin"},
+	"grid": [[1, 2], []],
+	"extra": {"any": [null]}
+}`
+
+func (s *OrderSuite) TestRoundTrip() {
+	order, err := UnmarshalOrder([]byte(orderJson), c5.RejectUnknownFields())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(7), order.ID)
+	assert.Equal(s.T(), OrderStatus_InTransit, *order.Status)
+	assert.Equal(s.T(), 1.5, order.Items[0].Attributes["weight"])
+	assert.Equal(s.T(), "Berlin", order.Shipping.City)
+	assert.Nil(s.T(), order.Shipping.Zip)
+	assert.Nil(s.T(), order.Note)
+	assert.Equal(s.T(), [][]int64{{1, 2}, {}}, order.Grid)
+
+	var again Order
+	assert.NoError(s.T(), FromDictOrder(order.ToDict(), &again, c5.RejectUnknownFields()))
+	assert.Equal(s.T(), *order, again)
+
+	out, err := again.Marshal()
+	assert.NoError(s.T(), err)
+	assert.JSONEq(s.T(), orderJson, string(out))
+}
+
+func (s *OrderSuite) TestNullIsAbsent() {
+	order, err := UnmarshalOrder([]byte(`{"id": 1, "customer": "c", "paid": false, "items": [], "note": null}`))
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), order.Note)
+	assert.NotContains(s.T(), order.ToDict(), "note")
+}
+
+func (s *OrderSuite) TestErrors() {
+	for _, tc := range []struct {
+		change func(map[string]interface{})
+		path   string
+		target interface{}
+	}{
+		{func(o map[string]interface{}) { delete(o, "paid") }, "/paid", &c5.MissingFieldError{}},
+		{func(o map[string]interface{}) { o["id"] = 1.5 }, "/id", &c5.FieldTypeError{}},
+		{func(o map[string]interface{}) { o["id"] = json.Number("1e3") }, "", nil},
+		{func(o map[string]interface{}) { o["items"].([]interface{})[0].(map[string]interface{})["count"] = "2" }, "/items/0/count", &c5.FieldTypeError{}},
+		{func(o map[string]interface{}) { delete(o["items"].([]interface{})[0].(map[string]interface{}), "sku") }, "/items/0/sku", &c5.MissingFieldError{}},
+		{func(o map[string]interface{}) { o["grid"] = []interface{}{[]interface{}{1, "x"}} }, "/grid/0/1", &c5.FieldTypeError{}},
+		{func(o map[string]interface{}) { o["tags"] = map[string]interface{}{"gift": true} }, "/tags/gift", &c5.FieldTypeError{}},
+		{func(o map[string]interface{}) { o["status"] = "lost" }, "/status", &c5.FieldError{}},
+		{func(o map[string]interface{}) { o["shipping"] = "Berlin" }, "/shipping", &c5.FieldTypeError{}},
+		{func(o map[string]interface{}) { o["shipping"].(map[string]interface{})["x"] = 1 }, "/shipping/x", &c5.UnknownFieldError{}},
+	} {
+		var dict map[string]interface{}
+		assert.NoError(s.T(), json.Unmarshal([]byte(orderJson), &dict))
+		tc.change(dict)
+		err := FromDictOrder(dict, &Order{}, c5.RejectUnknownFields())
+		switch target := tc.target.(type) {
+		case nil:
+			assert.NoError(s.T(), err)
+		case *c5.MissingFieldError:
+			if assert.True(s.T(), errors.As(err, &target), tc.path) {
+				assert.Equal(s.T(), tc.path, target.Path)
+			}
+		case *c5.FieldTypeError:
+			if assert.True(s.T(), errors.As(err, &target), tc.path) {
+				assert.Equal(s.T(), tc.path, target.Path)
+			}
+		case *c5.FieldError:
+			if assert.True(s.T(), errors.As(err, &target), tc.path) {
+				assert.Equal(s.T(), tc.path, target.Path)
+			}
+		case *c5.UnknownFieldError:
+			if assert.True(s.T(), errors.As(err, &target), tc.path) {
+				assert.Equal(s.T(), tc.path, target.Path)
+			}
+		}
+	}
+}
+
+func TestOrderSuite(t *testing.T) {
+	suite.Run(t, new(OrderSuite))
+}
@@ -0,0 +1,65 @@
+// Command c5gen generates Go types from JSON Schema files, with the
+// Marshal, Unmarshal, ToDict and FromDict functions of pkg/envelope.go.
+//
+//	c5gen -package c5 -o envelope.go envelope.json
+//
+// Every object in $defs or definitions becomes a struct, an object
+// without properties a map, and a string enum a string type with
+// constants. The FromDict functions report missing fields and wrong
+// types with the error types of the c5 package.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	pkg := flag.String("package", "c5", "package of the generated code")
+	out := flag.String("o", "", "output file, stdout if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: c5gen [flags] schema.json...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	src, err := run(*pkg, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "c5gen: %v\n", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*out, src, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "c5gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the code of the schema files
+func run(pkg string, files []string) ([]byte, error) {
+	m := newModel()
+	sources := make([]string, len(files))
+	for idx, file := range files {
+		doc, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		sources[idx] = filepath.Base(file)
+		if err := m.addSchema(sources[idx], doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.resolve(); err != nil {
+		return nil, err
+	}
+	return generate(m, pkg, sources)
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type kind int
+
+const (
+	kindAny kind = iota
+	kindString
+	kindNumber
+	kindInteger
+	kindBoolean
+	kindArray
+	kindMap
+	kindRef
+)
+
+// goType is the type of a field or of the items of an array or map
+type goType struct {
+	kind kind
+	elem *goType // kindArray and kindMap
+	ref  string  // kindRef
+}
+
+type declKind int
+
+const (
+	declStruct declKind = iota
+	declEnum
+	declMap
+)
+
+type field struct {
+	json     string
+	name     string
+	doc      string
+	typ      *goType
+	required bool
+}
+
+// decl is a named type of the generated code
+type decl struct {
+	kind   declKind
+	name   string
+	doc    string
+	fields []field  // declStruct
+	values []string // declEnum
+	elem   *goType  // declMap
+	// where the decl comes from, for error messages
+	pointer string
+}
+
+type model struct {
+	decls map[string]*decl
+}
+
+// SchemaError points at the part of a schema c5gen can not translate.
+type SchemaError struct {
+	File    string
+	Pointer string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%v#%v: %v", e.File, e.Pointer, e.Message)
+}
+
+func newModel() *model {
+	return &model{decls: map[string]*decl{}}
+}
+
+type parser struct {
+	model *model
+	file  string
+}
+
+func (p *parser) fail(pointer string, format string, args ...interface{}) error {
+	return &SchemaError{File: p.file, Pointer: pointer, Message: fmt.Sprintf(format, args...)}
+}
+
+// addSchema adds the definitions of the JSON Schema document doc, and
+// the document itself if it is an object type with a title.
+func (m *model) addSchema(file string, doc []byte) error {
+	p := &parser{model: m, file: file}
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return p.fail("", "%v", err)
+	}
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		return p.fail("", "expected an object")
+	}
+	for _, defs := range []string{"$defs", "definitions"} {
+		raw, found := obj[defs]
+		if !found {
+			continue
+		}
+		entries, ok := raw.(map[string]interface{})
+		if !ok {
+			return p.fail("/"+defs, "expected an object")
+		}
+		for _, name := range sortedKeys(entries) {
+			if err := p.definition(goName(name), entries[name], "/"+defs+"/"+escapePointer(name)); err != nil {
+				return err
+			}
+		}
+	}
+	if _, found := obj["properties"]; found {
+		title, ok := obj["title"].(string)
+		if !ok {
+			return p.fail("", "a root with properties needs a title to name its type")
+		}
+		if err := p.definition(goName(title), root, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve checks that every $ref names a decl
+func (m *model) resolve() error {
+	for _, d := range m.decls {
+		types := []*goType{d.elem}
+		for _, f := range d.fields {
+			types = append(types, f.typ)
+		}
+		for _, t := range types {
+			for ; t != nil; t = t.elem {
+				if t.kind == kindRef && m.decls[t.ref] == nil {
+					return &SchemaError{Pointer: d.pointer, Message: fmt.Sprintf("'%v' is not defined", t.ref)}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) add(d *decl) error {
+	if prev, found := p.model.decls[d.name]; found {
+		return p.fail(d.pointer, "type '%v' is already defined at %v", d.name, prev.pointer)
+	}
+	p.model.decls[d.name] = d
+	return nil
+}
+
+func (p *parser) definition(name string, node interface{}, pointer string) error {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return p.fail(pointer, "expected an object")
+	}
+	doc, _ := obj["description"].(string)
+	if _, found := obj["enum"]; found {
+		values, err := p.enum(obj, pointer)
+		if err != nil {
+			return err
+		}
+		return p.add(&decl{kind: declEnum, name: name, doc: doc, values: values, pointer: pointer})
+	}
+	if typ, _ := obj["type"].(string); typ != "object" {
+		return p.fail(pointer, "only objects and enums can be defined")
+	}
+	if _, found := obj["properties"]; !found {
+		elem, err := p.additional(obj, pointer, name+"Value")
+		if err != nil {
+			return err
+		}
+		return p.add(&decl{kind: declMap, name: name, doc: doc, elem: elem, pointer: pointer})
+	}
+	d := &decl{kind: declStruct, name: name, doc: doc, pointer: pointer}
+	if err := p.add(d); err != nil {
+		return err
+	}
+	props, ok := obj["properties"].(map[string]interface{})
+	if !ok {
+		return p.fail(pointer+"/properties", "expected an object")
+	}
+	required := map[string]bool{}
+	if raw, found := obj["required"]; found {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return p.fail(pointer+"/required", "expected an array of strings")
+		}
+		for _, r := range list {
+			str, ok := r.(string)
+			if !ok {
+				return p.fail(pointer+"/required", "expected an array of strings")
+			}
+			required[str] = true
+		}
+	}
+	names := map[string]string{}
+	for _, key := range sortedKeys(props) {
+		f := field{json: key, name: goName(key), required: required[key]}
+		if prev, found := names[f.name]; found {
+			return p.fail(pointer+"/properties", "'%v' and '%v' are both named %v", prev, key, f.name)
+		}
+		names[f.name] = key
+		if prop, ok := props[key].(map[string]interface{}); ok {
+			f.doc, _ = prop["description"].(string)
+		}
+		var err error
+		f.typ, err = p.typeOf(props[key], pointer+"/properties/"+escapePointer(key), name+f.name)
+		if err != nil {
+			return err
+		}
+		d.fields = append(d.fields, f)
+	}
+	return nil
+}
+
+// typeOf translates the schema node, hint names the inline enums and
+// objects
+func (p *parser) typeOf(node interface{}, pointer string, hint string) (*goType, error) {
+	switch node := node.(type) {
+	case bool:
+		if !node {
+			return nil, p.fail(pointer, "false allows no value")
+		}
+		return &goType{kind: kindAny}, nil
+	case map[string]interface{}:
+		if ref, found := node["$ref"]; found {
+			str, _ := ref.(string)
+			for _, prefix := range []string{"#/$defs/", "#/definitions/"} {
+				if strings.HasPrefix(str, prefix) && len(str) > len(prefix) {
+					return &goType{kind: kindRef, ref: goName(unescapePointer(str[len(prefix):]))}, nil
+				}
+			}
+			return nil, p.fail(pointer+"/$ref", "only local references to definitions are supported")
+		}
+		if _, found := node["enum"]; found {
+			if err := p.definition(hint, node, pointer); err != nil {
+				return nil, err
+			}
+			return &goType{kind: kindRef, ref: hint}, nil
+		}
+		typ, found := node["type"]
+		if !found {
+			for _, key := range []string{"allOf", "anyOf", "oneOf", "not", "const"} {
+				if _, found := node[key]; found {
+					return nil, p.fail(pointer+"/"+key, "is not supported")
+				}
+			}
+			return &goType{kind: kindAny}, nil
+		}
+		switch typ {
+		case "string":
+			return &goType{kind: kindString}, nil
+		case "number":
+			return &goType{kind: kindNumber}, nil
+		case "integer":
+			return &goType{kind: kindInteger}, nil
+		case "boolean":
+			return &goType{kind: kindBoolean}, nil
+		case "array":
+			items, found := node["items"]
+			if !found {
+				items = true
+			}
+			elem, err := p.typeOf(items, pointer+"/items", hint+"Item")
+			if err != nil {
+				return nil, err
+			}
+			return &goType{kind: kindArray, elem: elem}, nil
+		case "object":
+			if _, found := node["properties"]; found {
+				if err := p.definition(hint, node, pointer); err != nil {
+					return nil, err
+				}
+				return &goType{kind: kindRef, ref: hint}, nil
+			}
+			elem, err := p.additional(node, pointer, hint+"Value")
+			if err != nil {
+				return nil, err
+			}
+			return &goType{kind: kindMap, elem: elem}, nil
+		}
+		return nil, p.fail(pointer+"/type", "type %v is not supported", typ)
+	}
+	return nil, p.fail(pointer, "expected a schema")
+}
+
+// additional is the type of the values of an object without properties
+func (p *parser) additional(obj map[string]interface{}, pointer string, hint string) (*goType, error) {
+	node, found := obj["additionalProperties"]
+	if !found {
+		node = true
+	}
+	return p.typeOf(node, pointer+"/additionalProperties", hint)
+}
+
+func (p *parser) enum(obj map[string]interface{}, pointer string) ([]string, error) {
+	list, ok := obj["enum"].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, p.fail(pointer+"/enum", "expected a non empty array")
+	}
+	values := make([]string, len(list))
+	consts := map[string]string{}
+	for idx, v := range list {
+		str, ok := v.(string)
+		if !ok {
+			return nil, p.fail(fmt.Sprintf("%v/enum/%d", pointer, idx), "only string enums are supported")
+		}
+		if prev, found := consts[goName(str)]; found {
+			return nil, p.fail(pointer+"/enum", "'%v' and '%v' are both named %v", prev, str, goName(str))
+		}
+		consts[goName(str)] = str
+		values[idx] = str
+	}
+	return values, nil
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")
+}
+
+// initialisms are kept upper case like golint wants them
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "DNS": true, "HTML": true, "HTTP": true,
+	"ID": true, "IP": true, "JSON": true, "TTL": true, "UID": true,
+	"URI": true, "URL": true, "UTC": true, "UUID": true, "XML": true,
+}
+
+// goName turns a JSON name into an exported Go name
+func goName(name string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(name)
+	for idx, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) ||
+			(idx+1 < len(runes) && unicode.IsLower(runes[idx+1]))):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+	var out strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			out.WriteString(upper)
+			continue
+		}
+		rs := []rune(w)
+		out.WriteRune(unicode.ToUpper(rs[0]))
+		out.WriteString(string(rs[1:]))
+	}
+	str := out.String()
+	if str == "" || !unicode.IsLetter([]rune(str)[0]) {
+		str = "X" + str
+	}
+	return str
+}
@@ -0,0 +1,149 @@
+package c5
+
+import (
+	"time"
+)
+
+// BuiltEnvelope is the finished form of a SimpleEnvelope. All of it is
+// computed by Build, it never changes afterwards and can be shared
+// between goroutines. The getters return copies.
+type BuiltEnvelope struct {
+	id       string
+	src      string
+	dst      []string
+	t        int64
+	tr       TimeResolution
+	ttl      int
+	via      []string
+	exp      *int64
+	hashed   []string
+	kind     string
+	data     map[string]interface{}
+	dataHash string
+	json     string
+}
+
+// Build renders the envelope, if not done yet, and returns its finished
+// form. The data is taken from the rendered JSON, so it is detached from
+// the map the envelope was created with; numbers are json.Number.
+func (s *SimpleEnvelope) Build() (*BuiltEnvelope, error) {
+	if _, err := s.lazy(); err != nil {
+		return nil, err
+	}
+	s.buildOnce.Do(func() {
+		s.built, s.buildErr = s.build()
+	})
+	return s.built, s.buildErr
+}
+
+func (s *SimpleEnvelope) build() (*BuiltEnvelope, error) {
+	data, err := jsonData([]byte(*s.envJsonString))
+	if err != nil {
+		return nil, err
+	}
+	dataMap, _ := data.(map[string]interface{})
+	env := s.Envelope
+	return &BuiltEnvelope{
+		id:       env.ID,
+		src:      env.Src,
+		dst:      append([]string{}, env.Dst...),
+		t:        s.simpleEnvelopeProps.T,
+		tr:       s.simpleEnvelopeProps.Tr,
+		ttl:      int(env.TTL),
+		via:      append([]string(nil), env.Via...),
+		exp:      env.Exp,
+		hashed:   append([]string(nil), env.Hashed...),
+		kind:     env.Data.Kind,
+		data:     dataMap,
+		dataHash: *s.DataJsonHash.Hash,
+		json:     *s.envJsonString,
+	}, nil
+}
+
+func (b *BuiltEnvelope) ID() string {
+	return b.id
+}
+
+func (b *BuiltEnvelope) Src() string {
+	return b.src
+}
+
+func (b *BuiltEnvelope) Dst() []string {
+	return append([]string{}, b.dst...)
+}
+
+func (b *BuiltEnvelope) Time() time.Time {
+	return b.tr.Time(b.t)
+}
+
+// Timestamp is the time of the envelope as it is in its JSON, in units of
+// TimeResolution.
+func (b *BuiltEnvelope) Timestamp() int64 {
+	return b.t
+}
+
+func (b *BuiltEnvelope) TimeResolution() TimeResolution {
+	return b.tr
+}
+
+// Expires returns the expiry time of the envelope, false if it has none.
+func (b *BuiltEnvelope) Expires() (time.Time, bool) {
+	if b.exp == nil {
+		return time.Time{}, false
+	}
+	return b.tr.Time(*b.exp), true
+}
+
+func (b *BuiltEnvelope) TTL() int {
+	return b.ttl
+}
+
+// Via lists the hops which forwarded the envelope, nil if none did.
+func (b *BuiltEnvelope) Via() []string {
+	return append([]string(nil), b.via...)
+}
+
+// Hashed lists the members the id covers, nil if it covers the data
+// only.
+func (b *BuiltEnvelope) Hashed() []string {
+	return append([]string(nil), b.hashed...)
+}
+
+func (b *BuiltEnvelope) Kind() string {
+	return b.kind
+}
+
+func (b *BuiltEnvelope) Data() map[string]interface{} {
+	return copyJson(b.data).(map[string]interface{})
+}
+
+// DataHash is the digest of the data, the part of the content id after
+// the time. It covers the members Hashed lists if there are any.
+func (b *BuiltEnvelope) DataHash() string {
+	return b.dataHash
+}
+
+// CanonicalJSON is the JSON of the envelope with sorted keys, as AsJson
+// renders it.
+func (b *BuiltEnvelope) CanonicalJSON() []byte {
+	return []byte(b.json)
+}
+
+// copyJson deep copies decoded JSON, its leaves are immutable.
+func copyJson(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			out[key] = copyJson(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = copyJson(val)
+		}
+		return out
+	}
+	return v
+}
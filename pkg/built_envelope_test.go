@@ -0,0 +1,113 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BuiltEnvelopeSuite struct {
+	suite.Suite
+}
+
+func (s *BuiltEnvelopeSuite) props() *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{
+				"date": "2021-05-20",
+				"name": "object",
+			},
+		},
+		Dst:           []string{"a", "b"},
+		TTL:           7,
+		TimeGenerator: mtimer,
+	}
+}
+
+func (s *BuiltEnvelopeSuite) TestGetters() {
+	se := NewSimpleEnvelope(s.props())
+	b, err := se.Build()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", b.ID())
+	assert.Equal(s.T(), "test case", b.Src())
+	assert.Equal(s.T(), []string{"a", "b"}, b.Dst())
+	assert.Equal(s.T(), time.UnixMilli(1624140000000), b.Time())
+	assert.Equal(s.T(), 7, b.TTL())
+	assert.Equal(s.T(), "test", b.Kind())
+	assert.Equal(s.T(), map[string]interface{}{"date": "2021-05-20", "name": "object"}, b.Data())
+	assert.Equal(s.T(), "BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", b.DataHash())
+	assert.Equal(s.T(), *se.AsJson(), string(b.CanonicalJSON()))
+}
+
+func (s *BuiltEnvelopeSuite) TestDataHashWithGivenID() {
+	props := s.props()
+	props.ID = "given"
+	b, err := NewSimpleEnvelope(props).Build()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "given", b.ID())
+	assert.Equal(s.T(), "BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", b.DataHash())
+}
+
+func (s *BuiltEnvelopeSuite) TestImmutable() {
+	props := s.props()
+	props.Data = PayloadT1{Kind: "test", Data: map[string]interface{}{
+		"list": []interface{}{1, map[string]interface{}{"x": "y"}},
+	}}
+	b, err := NewSimpleEnvelope(props).Build()
+	assert.NoError(s.T(), err)
+	props.Data.(PayloadT1).Data["list"] = "changed"
+	props.Dst[0] = "changed"
+
+	dst := b.Dst()
+	dst[1] = "changed"
+	data := b.Data()
+	data["list"].([]interface{})[1].(map[string]interface{})["x"] = "changed"
+	data["new"] = true
+	js := b.CanonicalJSON()
+	js[0] = 'X'
+
+	assert.Equal(s.T(), []string{"a", "b"}, b.Dst())
+	assert.Equal(s.T(), map[string]interface{}{
+		"list": []interface{}{json.Number("1"), map[string]interface{}{"x": "y"}},
+	}, b.Data())
+	assert.Equal(s.T(), byte('{'), b.CanonicalJSON()[0])
+}
+
+func (s *BuiltEnvelopeSuite) TestBuildOnce() {
+	se := NewSimpleEnvelope(s.props())
+	var wg sync.WaitGroup
+	built := make([]*BuiltEnvelope, 8)
+	for i := range built {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b, err := se.Build()
+			assert.NoError(s.T(), err)
+			built[i] = b
+		}(i)
+	}
+	wg.Wait()
+	for _, b := range built {
+		assert.Same(s.T(), built[0], b)
+	}
+}
+
+func (s *BuiltEnvelopeSuite) TestBuildError() {
+	props := s.props()
+	props.Data = PayloadT1{Kind: "test", Data: map[string]interface{}{"ch": make(chan int)}}
+	b, err := NewSimpleEnvelope(props).Build()
+	assert.Nil(s.T(), b)
+	var uerr *UnmarshalableValueError
+	assert.True(s.T(), errors.As(err, &uerr))
+}
+
+func TestBuiltEnvelopeSuite(t *testing.T) {
+	suite.Run(t, new(BuiltEnvelopeSuite))
+}
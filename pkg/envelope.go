@@ -1,124 +1,43 @@
-// This file was generated from JSON Schema using quicktype, do not modify it directly.
-// To parse and unparse this JSON data, add this code to your project and do:
-//
-//    payload, err := UnmarshalPayload(bytes)
-//    payload, err := FromDictPayload(map[string]interface{})
-//    bytes, err = payload.Marshal()
-//    map[string]interface{}, err = payload.ToDict()
-//
-//    envelope, err := UnmarshalEnvelope(bytes)
-//    envelope, err := FromDictEnvelope(map[string]interface{})
-//    bytes, err = envelope.Marshal()
-//    map[string]interface{}, err = envelope.ToDict()
-//
-//    sampleNameDate, err := UnmarshalSampleNameDate(bytes)
-//    sampleNameDate, err := FromDictSampleNameDate(map[string]interface{})
-//    bytes, err = sampleNameDate.Marshal()
-//    map[string]interface{}, err = sampleNameDate.ToDict()
-//
-//    sampleY, err := UnmarshalSampleY(bytes)
-//    sampleY, err := FromDictSampleY(map[string]interface{})
-//    bytes, err = sampleY.Marshal()
-//    map[string]interface{}, err = sampleY.ToDict()
-//
-//    payloadT, err := UnmarshalPayloadT(bytes)
-//    payloadT, err := FromDictPayloadT(map[string]interface{})
-//    bytes, err = payloadT.Marshal()
-//    map[string]interface{}, err = payloadT.ToDict()
-//
-//    t, err := UnmarshalT(bytes)
-//    t, err := FromDictT(map[string]interface{})
-//    bytes, err = t.Marshal()
-//    map[string]interface{}, err = t.ToDict()
-//
-//    envelopeT, err := UnmarshalEnvelopeT(bytes)
-//    envelopeT, err := FromDictEnvelopeT(map[string]interface{})
-//    bytes, err = envelopeT.Marshal()
-//    map[string]interface{}, err = envelopeT.ToDict()
-//
-//    payloadT1, err := UnmarshalPayloadT1(bytes)
-//    payloadT1, err := FromDictPayloadT1(map[string]interface{})
-//    bytes, err = payloadT1.Marshal()
-//    map[string]interface{}, err = payloadT1.ToDict()
-//
-//    t1, err := UnmarshalT1(bytes)
-//    t1, err := FromDictT1(map[string]interface{})
-//    bytes, err = t1.Marshal()
-//    map[string]interface{}, err = t1.ToDict()
+// Code generated by c5gen from envelope.json. DO NOT EDIT.
 
 package c5
 
-import "fmt"
-import "errors"
-import "encoding/json"
-
-type T map[string]interface{}
-
-type T1 map[string]interface{}
-
-type PayloadT struct {
-	Data map[string]interface{} `json:"data"`
-	Kind string                 `json:"kind"`
-}
-
-func (r *PayloadT) Marshal() ([]byte, error) {
-	return json.Marshal(r)
-}
-
-func UnmarshalPayloadT(data []byte) (*PayloadT, error) {
-	dict := map[string]interface{}{}
-	err := json.Unmarshal(data, &dict)
-	if err != nil {
-		return nil, err
-	}
-	ins := PayloadT{}
-	return &ins, FromDictPayloadT(dict, &ins)
-}
-
-func (r *PayloadT) ToDict() map[string]interface{} {
-	dict := map[string]interface{}{}
-	{
-		tmp := map[string]interface{}{}
-		for key, i := range r.Data {
-			tmp[key] = i.(interface{})
-		}
-		dict["data"] = tmp
-	}
-	dict["kind"] = r.Kind
-	return dict
-}
-
-func FromDictPayloadT(data map[string]interface{}, r *PayloadT) error {
-	r.Data = map[string]interface{}{}
-	for key, i := range data["data"].(map[string]interface{}) {
-		r.Data[key] = i.(interface{})
-	}
-	r.Kind = data["kind"].(string)
-	return nil
-}
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
 
 type EnvelopeT struct {
 	Data PayloadT1 `json:"data"`
-	Dst  []string  `json:"dst"` 
-	ID   string    `json:"id"`  
-	Src  string    `json:"src"` 
-	T    float64   `json:"t"`   
-	TTL  float64   `json:"ttl"` 
-	V    V         `json:"v"`   
+	Dst  []string  `json:"dst"`
+	// expires the envelope, in the units of tr
+	Exp *int64 `json:"exp,omitempty"`
+	// lists the members the id covers, type tagged, only data.data if absent
+	Hashed []string `json:"hashed,omitempty"`
+	ID     string   `json:"id"`
+	Src    string   `json:"src"`
+	// UTC time since 1970 in the units of tr
+	T  int64           `json:"t"`
+	Tr *TimeResolution `json:"tr,omitempty"`
+	// Limits the hop count
+	TTL float64 `json:"ttl"`
+	V   V       `json:"v"`
+	// lists the hops which forwarded the envelope
+	Via []string `json:"via,omitempty"`
 }
 
 func (r *EnvelopeT) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
-func UnmarshalEnvelopeT(data []byte) (*EnvelopeT, error) {
-	dict := map[string]interface{}{}
-	err := json.Unmarshal(data, &dict)
+func UnmarshalEnvelopeT(data []byte, opts ...FromDictOption) (*EnvelopeT, error) {
+	dict, err := DecodeDict(data)
 	if err != nil {
 		return nil, err
 	}
 	ins := EnvelopeT{}
-	return &ins, FromDictEnvelopeT(dict, &ins)
+	return &ins, FromDictEnvelopeT(dict, &ins, opts...)
 }
 
 func (r *EnvelopeT) ToDict() map[string]interface{} {
@@ -131,104 +50,248 @@ func (r *EnvelopeT) ToDict() map[string]interface{} {
 		}
 		dict["dst"] = tmp
 	}
+	if r.Exp != nil {
+		dict["exp"] = *r.Exp
+	}
+	if r.Hashed != nil {
+		tmp := make([]string, len(r.Hashed))
+		for idx, i := range r.Hashed {
+			tmp[idx] = i
+		}
+		dict["hashed"] = tmp
+	}
 	dict["id"] = r.ID
 	dict["src"] = r.Src
 	dict["t"] = r.T
+	if r.Tr != nil {
+		dict["tr"] = ToTimeResolution(*r.Tr)
+	}
 	dict["ttl"] = r.TTL
 	dict["v"] = ToV(r.V)
+	if r.Via != nil {
+		tmp := make([]string, len(r.Via))
+		for idx, i := range r.Via {
+			tmp[idx] = i
+		}
+		dict["via"] = tmp
+	}
 	return dict
 }
 
-func FromDictEnvelopeT(data map[string]interface{}, r *EnvelopeT) error {
+func FromDictEnvelopeT(data map[string]interface{}, r *EnvelopeT, opts ...FromDictOption) error {
+	return fromDictEnvelopeT(data, r, "", NewFromDictOptions(opts))
+}
+
+func fromDictEnvelopeT(data map[string]interface{}, r *EnvelopeT, path string, o *FromDictOptions) error {
+	if err := DictKnown(data, path, o, "data", "dst", "exp", "hashed", "id", "src", "t", "tr", "ttl", "v", "via"); err != nil {
+		return err
+	}
 	{
-		err := FromDictPayloadT1(data["data"].(map[string]interface{}), &r.Data)
+		v, err := DictField(data, "data", path)
+		if err != nil {
+			return err
+		}
+		obj, err := DictObject(v, path+"/data")
 		if err != nil {
 			return err
 		}
+		if err := fromDictPayloadT1(obj, &r.Data, path+"/data", o); err != nil {
+			return err
+		}
 	}
-	switch v := data["dst"].(type) {
-		case []interface{}: {
-			r.Dst= make([]string, len(v))
-			for idx, i := range v {
-				r.Dst[idx] = i.(string)
-			}
+	{
+		v, err := DictField(data, "dst", path)
+		if err != nil {
+			return err
+		}
+		arr, err := DictArray(v, path+"/dst")
+		if err != nil {
+			return err
 		}
-		case []string: {
-			r.Dst= make([]string, len(v))
-			for idx, i := range v {
-				r.Dst[idx] = i
+		r.Dst = make([]string, len(arr))
+		for idx, i := range arr {
+			x, err := DictString(i, path+"/dst"+"/"+strconv.Itoa(idx))
+			if err != nil {
+				return err
 			}
+			r.Dst[idx] = x
 		}
-		default: {
-			return errors.New(fmt.Sprintf("unknown array type:%T", v))
+	}
+	if v, found := data["exp"]; found && v != nil {
+		var item int64
+		x, err := DictInteger(v, path+"/exp")
+		if err != nil {
+			return err
 		}
+		item = x
+		r.Exp = &item
 	}
-	r.ID = data["id"].(string)
-	r.Src = data["src"].(string)
-	switch v := data["t"].(type) {
-		case int: {
-			r.T = float64(v)
+	if v, found := data["hashed"]; found && v != nil {
+		arr, err := DictArray(v, path+"/hashed")
+		if err != nil {
+			return err
+		}
+		r.Hashed = make([]string, len(arr))
+		for idx, i := range arr {
+			x, err := DictString(i, path+"/hashed"+"/"+strconv.Itoa(idx))
+			if err != nil {
+				return err
+			}
+			r.Hashed[idx] = x
 		}
-		case int32: {
-			r.T = float64(v)
+	}
+	{
+		v, err := DictField(data, "id", path)
+		if err != nil {
+			return err
 		}
-		case int64: {
-			r.T = float64(v)
+		x, err := DictString(v, path+"/id")
+		if err != nil {
+			return err
 		}
-		case uint: {
-			r.T = float64(v)
+		r.ID = x
+	}
+	{
+		v, err := DictField(data, "src", path)
+		if err != nil {
+			return err
 		}
-		case uint32: {
-			r.T = float64(v)
+		x, err := DictString(v, path+"/src")
+		if err != nil {
+			return err
 		}
-		case uint64: {
-			r.T = float64(v)
+		r.Src = x
+	}
+	{
+		v, err := DictField(data, "t", path)
+		if err != nil {
+			return err
 		}
-		case float32: {
-			r.T = float64(v)
+		x, err := DictInteger(v, path+"/t")
+		if err != nil {
+			return err
 		}
-		case float64: {
-			r.T = float64(v)
+		r.T = x
+	}
+	if v, found := data["tr"]; found && v != nil {
+		var item TimeResolution
+		s, err := DictString(v, path+"/tr")
+		if err != nil {
+			return err
 		}
-		default: {
-			return errors.New(fmt.Sprintf("unable to coerce number:%T", v))
+		e, err := FromTimeResolution(s)
+		if err != nil {
+			return &FieldError{Path: path + "/tr", Err: err}
 		}
+		item = e
+		r.Tr = &item
 	}
-	switch v := data["ttl"].(type) {
-		case int: {
-			r.TTL = float64(v)
+	{
+		v, err := DictField(data, "ttl", path)
+		if err != nil {
+			return err
 		}
-		case int32: {
-			r.TTL = float64(v)
+		x, err := DictNumber(v, path+"/ttl")
+		if err != nil {
+			return err
 		}
-		case int64: {
-			r.TTL = float64(v)
+		r.TTL = x
+	}
+	{
+		v, err := DictField(data, "v", path)
+		if err != nil {
+			return err
 		}
-		case uint: {
-			r.TTL = float64(v)
+		s, err := DictString(v, path+"/v")
+		if err != nil {
+			return err
 		}
-		case uint32: {
-			r.TTL = float64(v)
+		e, err := FromV(s)
+		if err != nil {
+			return &FieldError{Path: path + "/v", Err: err}
 		}
-		case uint64: {
-			r.TTL = float64(v)
+		r.V = e
+	}
+	if v, found := data["via"]; found && v != nil {
+		arr, err := DictArray(v, path+"/via")
+		if err != nil {
+			return err
 		}
-		case float32: {
-			r.TTL = float64(v)
+		r.Via = make([]string, len(arr))
+		for idx, i := range arr {
+			x, err := DictString(i, path+"/via"+"/"+strconv.Itoa(idx))
+			if err != nil {
+				return err
+			}
+			r.Via[idx] = x
 		}
-		case float64: {
-			r.TTL = float64(v)
+	}
+	return nil
+}
+
+type PayloadT struct {
+	Data map[string]interface{} `json:"data"`
+	Kind string                 `json:"kind"`
+}
+
+func (r *PayloadT) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalPayloadT(data []byte, opts ...FromDictOption) (*PayloadT, error) {
+	dict, err := DecodeDict(data)
+	if err != nil {
+		return nil, err
+	}
+	ins := PayloadT{}
+	return &ins, FromDictPayloadT(dict, &ins, opts...)
+}
+
+func (r *PayloadT) ToDict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	{
+		tmp := make(map[string]interface{}, len(r.Data))
+		for key, i := range r.Data {
+			tmp[key] = i
+		}
+		dict["data"] = tmp
+	}
+	dict["kind"] = r.Kind
+	return dict
+}
+
+func FromDictPayloadT(data map[string]interface{}, r *PayloadT, opts ...FromDictOption) error {
+	return fromDictPayloadT(data, r, "", NewFromDictOptions(opts))
+}
+
+func fromDictPayloadT(data map[string]interface{}, r *PayloadT, path string, o *FromDictOptions) error {
+	if err := DictKnown(data, path, o, "data", "kind"); err != nil {
+		return err
+	}
+	{
+		v, err := DictField(data, "data", path)
+		if err != nil {
+			return err
+		}
+		obj, err := DictObject(v, path+"/data")
+		if err != nil {
+			return err
 		}
-		default: {
-			return errors.New(fmt.Sprintf("unable to coerce number:%T", v))
+		r.Data = make(map[string]interface{}, len(obj))
+		for key, i := range obj {
+			r.Data[key] = i
 		}
 	}
 	{
-		var err error
-		r.V, err = FromV(data["v"].(string))
+		v, err := DictField(data, "kind", path)
+		if err != nil {
+			return err
+		}
+		x, err := DictString(v, path+"/kind")
 		if err != nil {
-			return err;
+			return err
 		}
+		r.Kind = x
 	}
 	return nil
 }
@@ -242,22 +305,21 @@ func (r *PayloadT1) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
-func UnmarshalPayloadT1(data []byte) (*PayloadT1, error) {
-	dict := map[string]interface{}{}
-	err := json.Unmarshal(data, &dict)
+func UnmarshalPayloadT1(data []byte, opts ...FromDictOption) (*PayloadT1, error) {
+	dict, err := DecodeDict(data)
 	if err != nil {
 		return nil, err
 	}
 	ins := PayloadT1{}
-	return &ins, FromDictPayloadT1(dict, &ins)
+	return &ins, FromDictPayloadT1(dict, &ins, opts...)
 }
 
 func (r *PayloadT1) ToDict() map[string]interface{} {
 	dict := map[string]interface{}{}
 	{
-		tmp := map[string]interface{}{}
+		tmp := make(map[string]interface{}, len(r.Data))
 		for key, i := range r.Data {
-			tmp[key] = i.(interface{})
+			tmp[key] = i
 		}
 		dict["data"] = tmp
 	}
@@ -265,12 +327,39 @@ func (r *PayloadT1) ToDict() map[string]interface{} {
 	return dict
 }
 
-func FromDictPayloadT1(data map[string]interface{}, r *PayloadT1) error {
-	r.Data = map[string]interface{}{}
-	for key, i := range data["data"].(map[string]interface{}) {
-		r.Data[key] = i.(interface{})
+func FromDictPayloadT1(data map[string]interface{}, r *PayloadT1, opts ...FromDictOption) error {
+	return fromDictPayloadT1(data, r, "", NewFromDictOptions(opts))
+}
+
+func fromDictPayloadT1(data map[string]interface{}, r *PayloadT1, path string, o *FromDictOptions) error {
+	if err := DictKnown(data, path, o, "data", "kind"); err != nil {
+		return err
+	}
+	{
+		v, err := DictField(data, "data", path)
+		if err != nil {
+			return err
+		}
+		obj, err := DictObject(v, path+"/data")
+		if err != nil {
+			return err
+		}
+		r.Data = make(map[string]interface{}, len(obj))
+		for key, i := range obj {
+			r.Data[key] = i
+		}
+	}
+	{
+		v, err := DictField(data, "kind", path)
+		if err != nil {
+			return err
+		}
+		x, err := DictString(v, path+"/kind")
+		if err != nil {
+			return err
+		}
+		r.Kind = x
 	}
-	r.Kind = data["kind"].(string)
 	return nil
 }
 
@@ -283,14 +372,13 @@ func (r *SampleNameDate) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
-func UnmarshalSampleNameDate(data []byte) (*SampleNameDate, error) {
-	dict := map[string]interface{}{}
-	err := json.Unmarshal(data, &dict)
+func UnmarshalSampleNameDate(data []byte, opts ...FromDictOption) (*SampleNameDate, error) {
+	dict, err := DecodeDict(data)
 	if err != nil {
 		return nil, err
 	}
 	ins := SampleNameDate{}
-	return &ins, FromDictSampleNameDate(dict, &ins)
+	return &ins, FromDictSampleNameDate(dict, &ins, opts...)
 }
 
 func (r *SampleNameDate) ToDict() map[string]interface{} {
@@ -300,9 +388,36 @@ func (r *SampleNameDate) ToDict() map[string]interface{} {
 	return dict
 }
 
-func FromDictSampleNameDate(data map[string]interface{}, r *SampleNameDate) error {
-	r.Date = data["date"].(string)
-	r.Name = data["name"].(string)
+func FromDictSampleNameDate(data map[string]interface{}, r *SampleNameDate, opts ...FromDictOption) error {
+	return fromDictSampleNameDate(data, r, "", NewFromDictOptions(opts))
+}
+
+func fromDictSampleNameDate(data map[string]interface{}, r *SampleNameDate, path string, o *FromDictOptions) error {
+	if err := DictKnown(data, path, o, "date", "name"); err != nil {
+		return err
+	}
+	{
+		v, err := DictField(data, "date", path)
+		if err != nil {
+			return err
+		}
+		x, err := DictString(v, path+"/date")
+		if err != nil {
+			return err
+		}
+		r.Date = x
+	}
+	{
+		v, err := DictField(data, "name", path)
+		if err != nil {
+			return err
+		}
+		x, err := DictString(v, path+"/name")
+		if err != nil {
+			return err
+		}
+		r.Name = x
+	}
 	return nil
 }
 
@@ -314,14 +429,13 @@ func (r *SampleY) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
-func UnmarshalSampleY(data []byte) (*SampleY, error) {
-	dict := map[string]interface{}{}
-	err := json.Unmarshal(data, &dict)
+func UnmarshalSampleY(data []byte, opts ...FromDictOption) (*SampleY, error) {
+	dict, err := DecodeDict(data)
 	if err != nil {
 		return nil, err
 	}
 	ins := SampleY{}
-	return &ins, FromDictSampleY(dict, &ins)
+	return &ins, FromDictSampleY(dict, &ins, opts...)
 }
 
 func (r *SampleY) ToDict() map[string]interface{} {
@@ -330,55 +444,75 @@ func (r *SampleY) ToDict() map[string]interface{} {
 	return dict
 }
 
-func FromDictSampleY(data map[string]interface{}, r *SampleY) error {
-	switch v := data["y"].(type) {
-		case int: {
-			r.Y = float64(v)
-		}
-		case int32: {
-			r.Y = float64(v)
-		}
-		case int64: {
-			r.Y = float64(v)
-		}
-		case uint: {
-			r.Y = float64(v)
-		}
-		case uint32: {
-			r.Y = float64(v)
-		}
-		case uint64: {
-			r.Y = float64(v)
-		}
-		case float32: {
-			r.Y = float64(v)
-		}
-		case float64: {
-			r.Y = float64(v)
+func FromDictSampleY(data map[string]interface{}, r *SampleY, opts ...FromDictOption) error {
+	return fromDictSampleY(data, r, "", NewFromDictOptions(opts))
+}
+
+func fromDictSampleY(data map[string]interface{}, r *SampleY, path string, o *FromDictOptions) error {
+	if err := DictKnown(data, path, o, "y"); err != nil {
+		return err
+	}
+	{
+		v, err := DictField(data, "y", path)
+		if err != nil {
+			return err
 		}
-		default: {
-			return errors.New(fmt.Sprintf("unable to coerce number:%T", v))
+		x, err := DictNumber(v, path+"/y")
+		if err != nil {
+			return err
 		}
+		r.Y = x
 	}
 	return nil
 }
 
+type T map[string]interface{}
+
+type T1 map[string]interface{}
+
+// TimeResolution is the unit of t, milliseconds if absent
+type TimeResolution string
+
+const (
+	TimeResolution_Ms TimeResolution = "ms"
+	TimeResolution_Us TimeResolution = "us"
+	TimeResolution_Ns TimeResolution = "ns"
+)
+
+func FromTimeResolution(v string) (TimeResolution, error) {
+	switch v {
+	case "ms":
+		return TimeResolution_Ms, nil
+	case "us":
+		return TimeResolution_Us, nil
+	case "ns":
+		return TimeResolution_Ns, nil
+	}
+	return "", fmt.Errorf("enum TimeResolution has no value '%v'", v)
+}
+
+func ToTimeResolution(v TimeResolution) string {
+	return string(v)
+}
+
+// V A: plain data hash, B: type tagged data hash
 type V string
+
 const (
 	V_A V = "A"
+	V_B V = "B"
 )
+
 func FromV(v string) (V, error) {
 	switch v {
-		case "A":
-			return V_A, nil
-		default:
-			return V_A, errors.New(fmt.Sprintf("Enum not found for:%s", v))
+	case "A":
+		return V_A, nil
+	case "B":
+		return V_B, nil
 	}
+	return "", fmt.Errorf("enum V has no value '%v'", v)
 }
+
 func ToV(v V) string {
-	switch v {
-		case V_A:
-			return "A"
-	}
-	panic("enum with a unkown value")
+	return string(v)
 }
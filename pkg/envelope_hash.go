@@ -0,0 +1,128 @@
+package c5
+
+import (
+	"sort"
+	"strings"
+)
+
+// hashableFields are the members of an envelope the id may cover, data
+// always is, id never and hashed whenever any is.
+var hashableFields = map[string]bool{
+	"data": true,
+	"dst":  true,
+	"exp":  true,
+	"src":  true,
+	"t":    true,
+	"tr":   true,
+	"ttl":  true,
+	"v":    true,
+	"via":  true,
+}
+
+// hashedOf returns the hashed member of an envelope whose id covers data
+// and fields, sorted and without doubles. It is nil, the id covers
+// data.data only, unless all is set or fields are given.
+func hashedOf(all bool, fields []string) ([]string, error) {
+	if !all && len(fields) == 0 {
+		return nil, nil
+	}
+	hashed := []string{"data"}
+	for _, field := range fields {
+		if !hashableFields[field] {
+			return nil, &UnhashableFieldError{Field: field}
+		}
+		if field != "data" {
+			hashed = append(hashed, field)
+		}
+	}
+	sort.Strings(hashed)
+	out := hashed[:1]
+	for _, field := range hashed[1:] {
+		if field != out[len(out)-1] {
+			out = append(out, field)
+		}
+	}
+	return out, nil
+}
+
+// checkHashed rejects a hashed member which does not cover data or names
+// a member the id can not cover.
+func checkHashed(hashed []string) error {
+	data := false
+	for _, field := range hashed {
+		if !hashableFields[field] {
+			return &UnhashableFieldError{Field: field}
+		}
+		data = data || field == "data"
+	}
+	if !data {
+		return &UnhashableFieldError{Field: "data", Missing: true}
+	}
+	return nil
+}
+
+// isHashedToken reports if sval belongs to a member hashed lists or to
+// hashed itself.
+func isHashedToken(sval SVal, hashed []string) bool {
+	if sval.path == "" {
+		return false
+	}
+	member := sval.path[1:]
+	if idx := strings.Index(member, "/"); idx >= 0 {
+		member = member[:idx]
+	}
+	if member == "hashed" {
+		return true
+	}
+	for _, field := range hashed {
+		if field == member {
+			return true
+		}
+	}
+	return false
+}
+
+// envelopeHash is the digest of the members of env its Hashed lists, the
+// tokens of SortKeys over the envelope without id. It is type tagged
+// whatever v says, the plain hash of V_A would let dst ["a","b"] pass for
+// ["ab"].
+func envelopeHash(env *Envelope[interface{}], spec HashSpec) (string, error) {
+	spec.Tagged = true
+	hashC, err := NewHashCollectorSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	err = SortKeysE(*env, func(sval SVal) error {
+		if !isHashedToken(sval, env.Hashed) {
+			return nil
+		}
+		return hashC.AppendE(sval)
+	})
+	if err != nil {
+		return "", err
+	}
+	return hashC.Digest(), nil
+}
+
+// contentHash is the digest the id of the header env derives from, with
+// data as its data.data.
+func contentHash(env *EnvelopeT, data interface{}, spec HashSpec) (string, error) {
+	if env.Hashed == nil {
+		return dataHash(data, spec)
+	}
+	if err := checkHashed(env.Hashed); err != nil {
+		return "", err
+	}
+	return envelopeHash(&Envelope[interface{}]{
+		Data:   Payload[interface{}]{Kind: env.Data.Kind, Data: data},
+		Dst:    env.Dst,
+		Exp:    env.Exp,
+		Hashed: env.Hashed,
+		Src:    env.Src,
+		T:      env.T,
+		Tr:     env.Tr,
+		TTL:    env.TTL,
+		Via:    env.Via,
+		V:      env.V,
+	}, spec)
+}
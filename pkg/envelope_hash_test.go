@@ -0,0 +1,178 @@
+package c5
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EnvelopeHashSuite struct {
+	suite.Suite
+}
+
+func (s *EnvelopeHashSuite) props(fields ...string) *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{"a", "b"},
+		T:             int64(1624140000000),
+		HashEnvelope:  true,
+		HashFields:    fields,
+		TimeGenerator: mtimer,
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"date": "2021-05-20", "name": "object"}},
+	}
+}
+
+func (s *EnvelopeHashSuite) mismatch(envJson string, opts ...ParseOption) {
+	var mismatch *IDMismatchError
+	_, err := ParseSimpleEnvelope([]byte(envJson), opts...)
+	assert.True(s.T(), errors.As(err, &mismatch), err)
+	_, err = ParseEnvelope[SampleNameDate]([]byte(envJson), opts...)
+	assert.True(s.T(), errors.As(err, &mismatch), err)
+	_, err = NewKindRegistry().Parse([]byte(envJson), opts...)
+	assert.True(s.T(), errors.As(err, &mismatch), err)
+}
+
+func (s *EnvelopeHashSuite) TestDefaultHashesData() {
+	props := s.props()
+	props.HashEnvelope = false
+	props.Dst = []string{}
+	se := NewSimpleEnvelope(props)
+	assert.Equal(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", se.AsEnvelope().ID)
+	assert.NotContains(s.T(), *se.AsJson(), `"hashed"`)
+	assert.Nil(s.T(), se.AsEnvelope().Hashed)
+}
+
+func (s *EnvelopeHashSuite) TestKind() {
+	se := NewSimpleEnvelope(s.props())
+	envJson := *se.AsJson()
+	assert.Contains(s.T(), envJson, `"hashed":["data"]`)
+	assert.True(s.T(), strings.HasPrefix(se.AsEnvelope().ID, "1624140000000-"))
+	assert.NotEqual(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", se.AsEnvelope().ID)
+	assert.NoError(s.T(), se.Verify())
+
+	parsed, err := ParseSimpleEnvelope([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), envJson, *parsed.AsJson())
+	assert.NoError(s.T(), parsed.Verify())
+
+	s.mismatch(strings.Replace(envJson, `"kind":"test"`, `"kind":"other"`, 1))
+	s.mismatch(strings.Replace(envJson, "2021-05-20", "2021-05-21", 1))
+	// the header is not covered
+	_, err = ParseSimpleEnvelope([]byte(strings.Replace(envJson, `"src":"test case"`, `"src":"other"`, 1)))
+	assert.NoError(s.T(), err)
+	// without hashed the id is taken for a data id
+	s.mismatch(strings.Replace(envJson, `"hashed":["data"],`, "", 1))
+}
+
+func (s *EnvelopeHashSuite) TestFields() {
+	se := NewSimpleEnvelope(s.props("ttl", "src", "dst", "src"))
+	envJson := *se.AsJson()
+	assert.Contains(s.T(), envJson, `"hashed":["data","dst","src","ttl"]`)
+	assert.Equal(s.T(), []string{"data", "dst", "src", "ttl"}, se.AsEnvelope().Hashed)
+
+	typed, err := ParseEnvelope[SampleNameDate]([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), typed.Verify())
+	registry := NewKindRegistry()
+	assert.NoError(s.T(), RegisterKind[SampleNameDate](registry, "test"))
+	_, err = registry.Parse([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), VerifyEnvelopeT(se.AsEnvelope()))
+
+	s.mismatch(strings.Replace(envJson, `"src":"test case"`, `"src":"other"`, 1))
+	s.mismatch(strings.Replace(envJson, `"dst":["a","b"]`, `"dst":["a"]`, 1))
+	s.mismatch(strings.Replace(envJson, `"ttl":10`, `"ttl":9`, 1))
+	s.mismatch(strings.Replace(envJson, `"hashed":["data","dst","src","ttl"]`, `"hashed":["data","dst","src"]`, 1))
+	_, err = ParseSimpleEnvelope([]byte(strings.Replace(envJson, `"t":1624140000000`, `"t":1624140000001`, 1)))
+	var mismatch *IDMismatchError
+	assert.True(s.T(), errors.As(err, &mismatch), err)
+
+	b, err := se.Build()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"data", "dst", "src", "ttl"}, b.Hashed())
+	assert.True(s.T(), strings.HasSuffix(se.AsEnvelope().ID, "-"+b.DataHash()))
+}
+
+func (s *EnvelopeHashSuite) TestFieldsImplyHashEnvelope() {
+	props := s.props("src")
+	props.HashEnvelope = false
+	assert.Equal(s.T(), []string{"data", "src"}, NewSimpleEnvelope(props).AsEnvelope().Hashed)
+}
+
+func (s *EnvelopeHashSuite) TestAllFields() {
+	props := s.props("dst", "exp", "src", "t", "tr", "ttl", "v", "via")
+	props.V = V_B
+	props.MaxAge = 60000000000
+	props.Via = []string{"relay"}
+	props.TimeResolution = TimeResolution_Us
+	props.DigestEncoding = BASE64URL
+	props.MacKey = []byte("key")
+	props.JsonProp = NewJsonProps(2, "")
+	se := NewSimpleEnvelope(props)
+	envJson := *se.AsJson()
+	parsed, err := ParseSimpleEnvelope([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), se.AsEnvelope(), parsed.AsEnvelope())
+	typed, err := ParseEnvelope[SampleNameDate]([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), typed.Verify())
+	_, err = VerifyMac([]byte(envJson), StaticMacKeyResolver{"": []byte("key")})
+	assert.NoError(s.T(), err)
+}
+
+func (s *EnvelopeHashSuite) TestIDGenerators() {
+	for _, g := range []IDGenerator{PureHashID{}, SourceKindHashID{}} {
+		props := s.props("src")
+		props.IDGenerator = g
+		envJson := *NewSimpleEnvelope(props).AsJson()
+		_, err := ParseSimpleEnvelope([]byte(envJson), WithIDGenerator(g))
+		assert.NoError(s.T(), err, "%T", g)
+		s.mismatch(strings.Replace(envJson, `"kind":"test"`, `"kind":"other"`, 1), WithIDGenerator(g))
+	}
+}
+
+func (s *EnvelopeHashSuite) TestUnhashable() {
+	var unhashable *UnhashableFieldError
+	for _, field := range []string{"id", "hashed", "mac", "kind", ""} {
+		_, err := NewSimpleEnvelopeE(s.props(field))
+		assert.True(s.T(), errors.As(err, &unhashable), field)
+		assert.Equal(s.T(), field, unhashable.Field)
+	}
+
+	envJson := *NewSimpleEnvelope(s.props("src")).AsJson()
+	for hashed, field := range map[string]string{
+		`"hashed":["src"]`:       "data",
+		`"hashed":[]`:            "data",
+		`"hashed":["data","id"]`: "id",
+	} {
+		_, err := ParseSimpleEnvelope([]byte(strings.Replace(envJson, `"hashed":["data","src"]`, hashed, 1)))
+		assert.True(s.T(), errors.As(err, &unhashable), hashed)
+		assert.Equal(s.T(), field, unhashable.Field, hashed)
+		assert.Equal(s.T(), field == "data", unhashable.Missing, hashed)
+	}
+}
+
+func (s *EnvelopeHashSuite) TestForward() {
+	se := NewSimpleEnvelope(s.props("src", "dst"))
+	fwd, err := Forward(se, "relay")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), se.AsEnvelope().ID, fwd.AsEnvelope().ID)
+	_, err = ParseSimpleEnvelope([]byte(*fwd.AsJson()))
+	assert.NoError(s.T(), err)
+
+	for _, field := range []string{"ttl", "via"} {
+		se := NewSimpleEnvelope(s.props(field))
+		_, err := Forward(se, "relay")
+		var hop *HashedHopError
+		assert.True(s.T(), errors.As(err, &hop), field)
+		assert.Equal(s.T(), field, hop.Field)
+		assert.Equal(s.T(), se.AsEnvelope().ID, hop.ID)
+	}
+}
+
+func TestEnvelopeHashSuite(t *testing.T) {
+	suite.Run(t, new(EnvelopeHashSuite))
+}
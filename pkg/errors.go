@@ -0,0 +1,291 @@
+package c5
+
+import (
+	"fmt"
+	"time"
+)
+
+// UnknownTimestampTypeError is returned if SimpleEnvelopeProps.T holds a
+// value which can not be turned into a timestamp.
+type UnknownTimestampTypeError struct {
+	Value interface{}
+}
+
+func (e *UnknownTimestampTypeError) Error() string {
+	return fmt.Sprintf("unhandled timestamp type:%T", e.Value)
+}
+
+// UnknownPayloadTypeError is returned if SimpleEnvelopeProps.Data holds a
+// value which is not a payload.
+type UnknownPayloadTypeError struct {
+	Value interface{}
+}
+
+func (e *UnknownPayloadTypeError) Error() string {
+	return fmt.Sprintf("unhandled payload type:%T", e.Value)
+}
+
+// UnmarshalableValueError is returned if a value at Path can not be
+// rendered as JSON.
+type UnmarshalableValueError struct {
+	Path string
+	Err  error
+}
+
+func (e *UnmarshalableValueError) Error() string {
+	return fmt.Sprintf("value at '%v' is not marshalable:%v", e.Path, e.Err)
+}
+
+func (e *UnmarshalableValueError) Unwrap() error {
+	return e.Err
+}
+
+// IDMismatchError is returned if the id of an envelope differs from the id
+// derived from its content.
+type IDMismatchError struct {
+	ID       string
+	Expected string
+}
+
+func (e *IDMismatchError) Error() string {
+	return fmt.Sprintf("envelope id '%v' does not match content id '%v'", e.ID, e.Expected)
+}
+
+// UnknownKeyError is returned by a KeyResolver if it has no key for KeyID.
+type UnknownKeyError struct {
+	KeyID string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("unknown key id '%v'", e.KeyID)
+}
+
+// UnsupportedAlgorithmError is returned if a signature names an algorithm
+// which is not implemented.
+type UnsupportedAlgorithmError struct {
+	Alg string
+}
+
+func (e *UnsupportedAlgorithmError) Error() string {
+	return fmt.Sprintf("unsupported algorithm '%v'", e.Alg)
+}
+
+// InvalidSignatureError is returned if a signature does not match the
+// envelope it is attached to.
+type InvalidSignatureError struct {
+	KeyID string
+}
+
+func (e *InvalidSignatureError) Error() string {
+	return fmt.Sprintf("invalid signature for key id '%v'", e.KeyID)
+}
+
+// InvalidMacError is returned if the mac of an envelope does not match.
+type InvalidMacError struct {
+	KeyID string
+}
+
+func (e *InvalidMacError) Error() string {
+	return fmt.Sprintf("invalid mac for key id '%v'", e.KeyID)
+}
+
+// UnsupportedEncodingError is returned if a digest encoding is not
+// implemented.
+type UnsupportedEncodingError struct {
+	Encoding string
+}
+
+func (e *UnsupportedEncodingError) Error() string {
+	return fmt.Sprintf("unsupported encoding '%v'", e.Encoding)
+}
+
+// UnknownKindError is returned by a KindRegistry for a payload kind which
+// is not registered.
+type UnknownKindError struct {
+	Kind string
+}
+
+func (e *UnknownKindError) Error() string {
+	return fmt.Sprintf("unknown payload kind '%v'", e.Kind)
+}
+
+// DuplicateKindError is returned if a kind or a type is registered twice.
+type DuplicateKindError struct {
+	Kind string
+}
+
+func (e *DuplicateKindError) Error() string {
+	return fmt.Sprintf("payload kind '%v' is already registered", e.Kind)
+}
+
+// KindDecodeError is returned if the data of a payload does not decode
+// into the type registered for its kind.
+type KindDecodeError struct {
+	Kind string
+	Err  error
+}
+
+func (e *KindDecodeError) Error() string {
+	return fmt.Sprintf("payload kind '%v' does not decode:%v", e.Kind, e.Err)
+}
+
+func (e *KindDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidSchemaError is returned if a JSON Schema document does not
+// compile, Pointer locates the keyword within the document.
+type InvalidSchemaError struct {
+	Pointer string
+	Message string
+}
+
+func (e *InvalidSchemaError) Error() string {
+	return fmt.Sprintf("invalid schema at '%v':%v", e.Pointer, e.Message)
+}
+
+// SchemaViolation is one mismatch of a payload with its schema, Path is the
+// path SortKeys reports for the value, e.g. /data/data/items/0.
+type SchemaViolation struct {
+	Path    string
+	Keyword string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("%v: %v", v.Path, v.Message)
+}
+
+// ValidationError is returned if the data of a payload does not match the
+// schema of its kind.
+type ValidationError struct {
+	Kind       string
+	Violations []SchemaViolation
+}
+
+func (e *ValidationError) Error() string {
+	more := ""
+	if len(e.Violations) > 1 {
+		more = fmt.Sprintf(" (and %d more)", len(e.Violations)-1)
+	}
+	return fmt.Sprintf("payload kind '%v' is invalid:%v%v", e.Kind, e.Violations[0], more)
+}
+
+// MissingFieldError is returned by the FromDict functions if a required
+// field is absent.
+type MissingFieldError struct {
+	Path string
+}
+
+func (e *MissingFieldError) Error() string {
+	return fmt.Sprintf("missing field '%v'", e.Path)
+}
+
+// FieldTypeError is returned by the FromDict functions if a field has the
+// wrong type.
+type FieldTypeError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *FieldTypeError) Error() string {
+	return fmt.Sprintf("field '%v' expected %v, got %v", e.Path, e.Expected, e.Actual)
+}
+
+// UnknownFieldError is returned by the FromDict functions for a field the
+// type does not declare, if RejectUnknownFields is set.
+type UnknownFieldError struct {
+	Path string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field '%v'", e.Path)
+}
+
+// FieldError is returned by the FromDict functions if the value of a field
+// is invalid.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field '%v':%v", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// TTLExpiredError is returned by Forward if the hop limit of the envelope
+// is used up.
+type TTLExpiredError struct {
+	ID  string
+	TTL int
+}
+
+func (e *TTLExpiredError) Error() string {
+	return fmt.Sprintf("envelope '%v' with ttl %v can not be forwarded", e.ID, e.TTL)
+}
+
+// UnhashableFieldError is returned for a field the id of an envelope can
+// not cover, or if hashed misses data, which it always covers.
+type UnhashableFieldError struct {
+	Field   string
+	Missing bool
+}
+
+func (e *UnhashableFieldError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("hashed does not list '%v'", e.Field)
+	}
+	return fmt.Sprintf("the id can not cover '%v'", e.Field)
+}
+
+// HashedHopError is returned by Forward if the id of the envelope covers
+// a field forwarding changes.
+type HashedHopError struct {
+	ID    string
+	Field string
+}
+
+func (e *HashedHopError) Error() string {
+	return fmt.Sprintf("envelope '%v' can not be forwarded, its id covers '%v'", e.ID, e.Field)
+}
+
+// EnvelopeExpiredError is returned by Freshness if the envelope expired,
+// by its exp or by the MaxAge.
+type EnvelopeExpiredError struct {
+	ID      string
+	Expired time.Time
+	Now     time.Time
+}
+
+func (e *EnvelopeExpiredError) Error() string {
+	return fmt.Sprintf("envelope '%v' expired at %v, now is %v",
+		e.ID, e.Expired.Format(time.RFC3339Nano), e.Now.Format(time.RFC3339Nano))
+}
+
+// EnvelopeFromFutureError is returned by Freshness if the time of the
+// envelope is ahead of the clock by more than the skew.
+type EnvelopeFromFutureError struct {
+	ID   string
+	Time time.Time
+	Now  time.Time
+}
+
+func (e *EnvelopeFromFutureError) Error() string {
+	return fmt.Sprintf("envelope '%v' is from %v, now is %v",
+		e.ID, e.Time.Format(time.RFC3339Nano), e.Now.Format(time.RFC3339Nano))
+}
+
+// ReplayedEnvelopeError is returned by ReplayGuard for an envelope id it
+// has seen within its window.
+type ReplayedEnvelopeError struct {
+	ID string
+}
+
+func (e *ReplayedEnvelopeError) Error() string {
+	return fmt.Sprintf("envelope '%v' was seen before", e.ID)
+}
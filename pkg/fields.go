@@ -0,0 +1,204 @@
+package c5
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// structField is a field of a struct as encoding/json sees it.
+type structField struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]structField
+
+// cachedFields returns the fields encoding/json would marshal for t,
+// including the ones promoted from embedded structs.
+func cachedFields(t reflect.Type) []structField {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]structField)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.([]structField)
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := map[string]bool{}
+	for _, opt := range parts[1:] {
+		opts[opt] = true
+	}
+	return parts[0], opts
+}
+
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// allowed punctuation, backslash and quotes are not
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+func typeFields(t reflect.Type) []structField {
+	type candidate struct {
+		typ   reflect.Type
+		index []int
+	}
+	current := []candidate{}
+	next := []candidate{{typ: t}}
+	visited := map[reflect.Type]bool{}
+	// names found on a shallower level hide the deeper ones, even if they
+	// were dropped as ambiguous
+	hidden := map[string]bool{}
+	var fields []structField
+
+	// breadth first, so shallower fields are found first
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count := map[string]int{}
+		// a type embedded twice on the same depth has all its fields twice,
+		// so they are ambiguous
+		times := map[reflect.Type]int{}
+		for _, c := range current {
+			times[c.typ]++
+		}
+		var level []structField
+		for _, c := range current {
+			if visited[c.typ] {
+				continue
+			}
+			visited[c.typ] = true
+			for i := 0; i < c.typ.NumField(); i++ {
+				sf := c.typ.Field(i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseTag(tag)
+				if !isValidTag(name) {
+					name = ""
+				}
+				index := make([]int, len(c.index)+1)
+				copy(index, c.index)
+				index[len(c.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct && ft != timeType {
+					next = append(next, candidate{typ: ft, index: index})
+					continue
+				}
+				quoted := false
+				if opts["string"] {
+					switch ft.Kind() {
+					case reflect.Bool,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+						reflect.Float32, reflect.Float64,
+						reflect.String:
+						quoted = true
+					}
+				}
+				f := structField{
+					name:      name,
+					index:     index,
+					tagged:    name != "",
+					omitEmpty: opts["omitempty"],
+					quoted:    quoted,
+				}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				for n := 0; n < times[c.typ]; n++ {
+					level = append(level, f)
+					count[f.name]++
+				}
+			}
+		}
+		// a name found more than once on the same depth is only kept if
+		// exactly one of them is tagged
+		for _, f := range level {
+			if hidden[f.name] {
+				continue
+			}
+			hidden[f.name] = true
+			if count[f.name] == 1 {
+				fields = append(fields, f)
+				continue
+			}
+			var dominant []structField
+			for _, o := range level {
+				if o.name == f.name && o.tagged {
+					dominant = append(dominant, o)
+				}
+			}
+			if len(dominant) == 1 {
+				fields = append(fields, dominant[0])
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].name < fields[j].name
+	})
+	return fields
+}
+
+// fieldByIndex follows index through embedded pointers, a nil pointer on
+// the way means the field is not there.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
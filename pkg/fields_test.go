@@ -0,0 +1,164 @@
+package c5
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FieldsSuite struct {
+	suite.Suite
+}
+
+func (s *FieldsSuite) sorted(e interface{}) string {
+	var out strings.Builder
+	col := NewJsonCollector(func(str string) {
+		out.WriteString(str)
+	}, nil)
+	assert.NoError(s.T(), SortKeysE(e, col.AppendE))
+	return out.String()
+}
+
+func (s *FieldsSuite) assertLikeMarshal(e interface{}) string {
+	ref, err := json.Marshal(e)
+	assert.NoError(s.T(), err)
+	out := s.sorted(e)
+	assert.JSONEq(s.T(), string(ref), out)
+	return out
+}
+
+type Inner struct {
+	Name   string `json:"name"`
+	Shared int    `json:"shared"`
+}
+
+type inner struct {
+	Hidden string `json:"hidden"`
+	Level  int
+}
+
+type Deep struct {
+	Shared int `json:"shared"`
+	Only   int `json:"only"`
+}
+
+type Middle struct {
+	Deep
+}
+
+type Tagged struct {
+	A int            `json:"a,omitempty"`
+	B int            `json:"-"`
+	C int            `json:"-,"`
+	D int            `json:",omitempty"`
+	E int            `json:"e,string"`
+	F bool           `json:"f,string"`
+	G string         `json:"g,string"`
+	H *int           `json:"h,string"`
+	I []int          `json:"i,omitempty"`
+	J map[string]int `json:"j,omitempty"`
+	K *Inner         `json:"k,omitempty"`
+	L interface{}    `json:"l,omitempty"`
+	M string         `json:"m,omitempty"`
+	N float64        `json:"n,omitempty"`
+	O time.Time      `json:"o"`
+	q int
+}
+
+func (s *FieldsSuite) TestTags() {
+	seven := 7
+	out := s.assertLikeMarshal(Tagged{C: 3, E: 4, F: true, G: "x", H: &seven, O: time.UnixMilli(444).UTC(), q: 9})
+	assert.Equal(s.T(), `{"-":3,"e":"4","f":"true","g":"\"x\"","h":"7","o":"1970-01-01T00:00:00.444Z"}`, out)
+	s.assertLikeMarshal(Tagged{A: 1, D: 2, I: []int{1}, J: map[string]int{"a": 1}, K: &Inner{}, L: 0, M: "m", N: 0.5})
+	s.assertLikeMarshal(&Tagged{})
+}
+
+func (s *FieldsSuite) TestEmbedded() {
+	type Outer struct {
+		Inner
+		inner
+		*Middle
+		Own string `json:"own"`
+	}
+	out := s.assertLikeMarshal(Outer{
+		Inner:  Inner{Name: "n", Shared: 1},
+		inner:  inner{Hidden: "h", Level: 2},
+		Middle: &Middle{Deep: Deep{Shared: 3, Only: 4}},
+		Own:    "o",
+	})
+	// Shared of Inner is shallower than the one of Deep
+	assert.Equal(s.T(), `{"Level":2,"hidden":"h","name":"n","only":4,"own":"o","shared":1}`, out)
+	// nil embedded pointers just drop their fields
+	s.assertLikeMarshal(Outer{Own: "o"})
+	// map values can not be addressed, promoted fields read all the same
+	s.assertLikeMarshal(map[string]Outer{"o": {inner: inner{Hidden: "h", Level: 2}}})
+}
+
+func (s *FieldsSuite) TestEmbeddedConflicts() {
+	type A struct {
+		X int
+		Y int `json:"y"`
+	}
+	type B struct {
+		X int
+		Y int
+	}
+	type Conflict struct {
+		A
+		B
+	}
+	out := s.assertLikeMarshal(Conflict{A: A{X: 1, Y: 2}, B: B{X: 3, Y: 4}})
+	// X is ambiguous and dropped, the tagged y wins over the untagged Y
+	assert.Equal(s.T(), `{"Y":4,"y":2}`, out)
+
+	type Named struct {
+		A `json:"a"`
+		B
+	}
+	s.assertLikeMarshal(Named{A: A{X: 1}, B: B{X: 2}})
+}
+
+func (s *FieldsSuite) TestEmbeddedTwice() {
+	type C struct{ X int }
+	type A struct{ C }
+	type B struct{ C }
+	type Twice struct {
+		A
+		B
+	}
+	// X is there twice on the same depth and dropped
+	assert.Equal(s.T(), "{}", s.assertLikeMarshal(Twice{}))
+}
+
+func (s *FieldsSuite) TestBytes() {
+	type Raw []byte
+	type Bytes struct {
+		B []byte  `json:"b"`
+		N []byte  `json:"n"`
+		R Raw     `json:"r"`
+		A [2]byte `json:"a"`
+	}
+	out := s.assertLikeMarshal(Bytes{B: []byte("hi"), R: Raw("r"), A: [2]byte{1, 2}})
+	assert.Equal(s.T(), `{"a":[1,2],"b":"aGk=","n":null,"r":"cg=="}`, out)
+}
+
+func (s *FieldsSuite) TestHiddenByAmbiguity() {
+	type X1 struct{ V int }
+	type X2 struct{ V int }
+	type Deeper struct{ V int }
+	type Wrap struct{ Deeper }
+	type Hidden struct {
+		X1
+		X2
+		Wrap
+	}
+	assert.Equal(s.T(), "{}", s.assertLikeMarshal(Hidden{X1{1}, X2{2}, Wrap{Deeper{3}}}))
+}
+
+func TestFieldsSuite(t *testing.T) {
+	suite.Run(t, new(FieldsSuite))
+}
@@ -0,0 +1,31 @@
+package c5
+
+// Forward returns the envelope a relay passes on: env with its TTL
+// decremented and, if via is not empty, via appended to its hops. The id
+// stays the one of env, it derives from the content which does not
+// change. An envelope whose TTL reached zero is rejected with a
+// TTLExpiredError, one whose id covers ttl or via with a HashedHopError.
+// A mac is kept only if env has its key, a parsed envelope is forwarded
+// without.
+func Forward(env *SimpleEnvelope, via string) (*SimpleEnvelope, error) {
+	rendered, err := env.AsEnvelopeE()
+	if err != nil {
+		return nil, err
+	}
+	ttl := int(rendered.TTL)
+	if ttl <= 0 {
+		return nil, &TTLExpiredError{ID: rendered.ID, TTL: ttl}
+	}
+	for _, field := range rendered.Hashed {
+		if field == "ttl" || field == "via" {
+			return nil, &HashedHopError{ID: rendered.ID, Field: field}
+		}
+	}
+	props := *env.simpleEnvelopeProps
+	props.ID = rendered.ID
+	props.TTL = ttl - 1
+	if via != "" {
+		props.Via = append(append([]string(nil), props.Via...), via)
+	}
+	return &SimpleEnvelope{simpleEnvelopeProps: &props}, nil
+}
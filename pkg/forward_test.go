@@ -0,0 +1,123 @@
+package c5
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ForwardSuite struct {
+	suite.Suite
+}
+
+func (s *ForwardSuite) envelope(ttl int) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{"a"},
+		TTL:           ttl,
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"date": "2021-05-20", "name": "object"}},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *ForwardSuite) TestForward() {
+	se := s.envelope(3)
+	fwd, err := Forward(se, "relay-1")
+	assert.NoError(s.T(), err)
+	env := fwd.AsEnvelope()
+	assert.Equal(s.T(), se.AsEnvelope().ID, env.ID)
+	assert.Equal(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", env.ID)
+	assert.Equal(s.T(), float64(2), env.TTL)
+	assert.Equal(s.T(), []string{"relay-1"}, env.Via)
+	assert.NoError(s.T(), fwd.Verify())
+	assert.Nil(s.T(), se.AsEnvelope().Via)
+	assert.Equal(s.T(), float64(3), se.AsEnvelope().TTL)
+
+	fwd, err = Forward(fwd, "")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), float64(1), fwd.AsEnvelope().TTL)
+	assert.Equal(s.T(), []string{"relay-1"}, fwd.AsEnvelope().Via)
+
+	fwd, err = Forward(fwd, "relay-3")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), float64(0), fwd.AsEnvelope().TTL)
+	assert.Contains(s.T(), *fwd.AsJson(), `"ttl":0`)
+
+	_, err = Forward(fwd, "relay-4")
+	var terr *TTLExpiredError
+	assert.True(s.T(), errors.As(err, &terr))
+	assert.Equal(s.T(), env.ID, terr.ID)
+	assert.Equal(s.T(), 0, terr.TTL)
+}
+
+func (s *ForwardSuite) TestParsedZeroTTL() {
+	envJson := strings.Replace(*s.envelope(3).AsJson(), `"ttl":3`, `"ttl":0`, 1)
+	parsed, err := ParseSimpleEnvelope([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), envJson, *parsed.AsJson())
+	_, err = Forward(parsed, "relay")
+	var terr *TTLExpiredError
+	assert.True(s.T(), errors.As(err, &terr), err)
+
+	parsed, err = ParseSimpleEnvelope([]byte(strings.Replace(envJson, `"ttl":0`, `"ttl":1`, 1)))
+	assert.NoError(s.T(), err)
+	fwd, err := Forward(parsed, "relay")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), float64(0), fwd.AsEnvelope().TTL)
+}
+
+func (s *ForwardSuite) TestDefaultTTL() {
+	fwd, err := Forward(s.envelope(0), "relay")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), float64(9), fwd.AsEnvelope().TTL)
+}
+
+func (s *ForwardSuite) TestHopsDoNotAlias() {
+	fwd, err := Forward(s.envelope(5), "a")
+	assert.NoError(s.T(), err)
+	b, err := Forward(fwd, "b")
+	assert.NoError(s.T(), err)
+	c, err := Forward(fwd, "c")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"a", "b"}, b.AsEnvelope().Via)
+	assert.Equal(s.T(), []string{"a", "c"}, c.AsEnvelope().Via)
+}
+
+func (s *ForwardSuite) TestParsed() {
+	fwd, err := Forward(s.envelope(5), "relay-1")
+	assert.NoError(s.T(), err)
+	envJson := *fwd.AsJson()
+	assert.Contains(s.T(), envJson, `"ttl":4,"v":"A","via":["relay-1"]`)
+	parsed, err := ParseSimpleEnvelope([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), envJson, *parsed.AsJson())
+	again, err := Forward(parsed, "relay-2")
+	assert.NoError(s.T(), err)
+	b, err := again.Build()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"relay-1", "relay-2"}, b.Via())
+	assert.Equal(s.T(), 3, b.TTL())
+}
+
+func (s *ForwardSuite) TestMacCoversHops() {
+	props := &SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{},
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+		TimeGenerator: mtimer,
+		MacKey:        []byte("key"),
+	}
+	se := NewSimpleEnvelope(props)
+	fwd, err := Forward(se, "relay")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), se.AsEnvelope().ID, fwd.AsEnvelope().ID)
+	assert.NotNil(s.T(), fwd.Mac)
+	assert.NotEqual(s.T(), *se.Mac, *fwd.Mac)
+}
+
+func TestForwardSuite(t *testing.T) {
+	suite.Run(t, new(ForwardSuite))
+}
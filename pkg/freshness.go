@@ -0,0 +1,53 @@
+package c5
+
+import (
+	"time"
+)
+
+// Freshness rejects envelopes which are expired or stamped too far in the
+// future, like a replay window does. The zero value only checks exp and
+// the future with no skew allowed.
+type Freshness struct {
+	// MaxAge rejects envelopes older than MaxAge, 0 for no limit
+	MaxAge time.Duration
+	// ClockSkew is the difference allowed between the clocks of sender
+	// and receiver, in both directions
+	ClockSkew time.Duration
+	// TimeGenerator is the clock of the receiver, defaults to the real one
+	TimeGenerator TimeGenerator
+}
+
+func (f *Freshness) now() time.Time {
+	if f.TimeGenerator == nil {
+		return time.Now()
+	}
+	return f.TimeGenerator.Now()
+}
+
+// Check rejects env with an EnvelopeFromFutureError if its time is after
+// now plus the skew, with an EnvelopeExpiredError if its exp or the
+// MaxAge after its time passed before now minus the skew.
+func (f *Freshness) Check(env *EnvelopeT) error {
+	now := f.now()
+	t := env.Time()
+	if t.After(now.Add(f.ClockSkew)) {
+		return &EnvelopeFromFutureError{ID: env.ID, Time: t, Now: now}
+	}
+	past := now.Add(-f.ClockSkew)
+	if exp, ok := env.Expires(); ok && past.After(exp) {
+		return &EnvelopeExpiredError{ID: env.ID, Expired: exp, Now: now}
+	}
+	if f.MaxAge > 0 && past.After(t.Add(f.MaxAge)) {
+		return &EnvelopeExpiredError{ID: env.ID, Expired: t.Add(f.MaxAge), Now: now}
+	}
+	return nil
+}
+
+// CheckEnvelope is Check for the envelope s renders.
+func (f *Freshness) CheckEnvelope(s *SimpleEnvelope) error {
+	env, err := s.AsEnvelopeE()
+	if err != nil {
+		return err
+	}
+	return f.Check(env)
+}
@@ -0,0 +1,125 @@
+package c5
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FreshnessSuite struct {
+	suite.Suite
+}
+
+// clock is a TimeGenerator at a settable time
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+// at returns a clock offset from the time of mtimer
+func at(offset time.Duration) *clock {
+	return &clock{now: mtimer.Now().Add(offset)}
+}
+
+func (s *FreshnessSuite) envelope(maxAge time.Duration, tr TimeResolution) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:            "test case",
+		Dst:            []string{},
+		Data:           PayloadT1{Kind: "test", Data: map[string]interface{}{"date": "2021-05-20", "name": "object"}},
+		TimeGenerator:  mtimer,
+		TimeResolution: tr,
+		MaxAge:         maxAge,
+	})
+}
+
+func (s *FreshnessSuite) TestExp() {
+	se := s.envelope(time.Minute, "")
+	env := se.AsEnvelope()
+	assert.Equal(s.T(), int64(1624140060000), *env.Exp)
+	assert.Contains(s.T(), *se.AsJson(), `"exp":1624140060000,"id":"1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp"`)
+	exp, ok := env.Expires()
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), mtimer.Now().Add(time.Minute), exp)
+
+	b, err := se.Build()
+	assert.NoError(s.T(), err)
+	exp, ok = b.Expires()
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), mtimer.Now().Add(time.Minute), exp)
+
+	_, ok = s.envelope(0, "").AsEnvelope().Expires()
+	assert.False(s.T(), ok)
+	assert.NotContains(s.T(), *s.envelope(0, "").AsJson(), `"exp"`)
+	assert.Equal(s.T(), int64(1624140000000000000+int64(time.Second)),
+		*s.envelope(time.Second, TimeResolution_Ns).AsEnvelope().Exp)
+}
+
+func (s *FreshnessSuite) TestCheck() {
+	withExp := s.envelope(time.Minute, TimeResolution_Us)
+	plain := s.envelope(0, "")
+	var expired *EnvelopeExpiredError
+	var future *EnvelopeFromFutureError
+
+	f := &Freshness{TimeGenerator: at(59 * time.Second)}
+	assert.NoError(s.T(), f.CheckEnvelope(withExp))
+	assert.NoError(s.T(), f.CheckEnvelope(plain))
+
+	f = &Freshness{TimeGenerator: at(61 * time.Second)}
+	err := f.CheckEnvelope(withExp)
+	if assert.True(s.T(), errors.As(err, &expired)) {
+		assert.Equal(s.T(), withExp.AsEnvelope().ID, expired.ID)
+		assert.Equal(s.T(), mtimer.Now().Add(time.Minute), expired.Expired)
+	}
+	f.ClockSkew = 2 * time.Second
+	assert.NoError(s.T(), f.CheckEnvelope(withExp))
+
+	f = &Freshness{TimeGenerator: at(11 * time.Second), MaxAge: 10 * time.Second}
+	assert.True(s.T(), errors.As(f.CheckEnvelope(plain), &expired))
+	assert.Equal(s.T(), mtimer.Now().Add(10*time.Second), expired.Expired)
+	assert.True(s.T(), errors.As(f.CheckEnvelope(withExp), &expired))
+
+	f = &Freshness{TimeGenerator: at(-time.Second)}
+	assert.True(s.T(), errors.As(f.CheckEnvelope(plain), &future))
+	assert.Equal(s.T(), mtimer.Now(), future.Time)
+	f.ClockSkew = time.Second
+	assert.NoError(s.T(), f.CheckEnvelope(plain))
+}
+
+func (s *FreshnessSuite) TestParse() {
+	se := s.envelope(time.Minute, "")
+	envJson := []byte(*se.AsJson())
+
+	parsed, err := ParseSimpleEnvelope(envJson, WithFreshness(&Freshness{TimeGenerator: at(time.Second)}))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), string(envJson), *parsed.AsJson())
+
+	var expired *EnvelopeExpiredError
+	late := WithFreshness(&Freshness{TimeGenerator: at(time.Hour)})
+	_, err = ParseSimpleEnvelope(envJson, late)
+	assert.True(s.T(), errors.As(err, &expired))
+	_, err = ParseEnvelope[map[string]interface{}](envJson, late)
+	assert.True(s.T(), errors.As(err, &expired))
+	registry := NewKindRegistry()
+	assert.NoError(s.T(), RegisterKind[map[string]interface{}](registry, "test"))
+	_, err = registry.Parse(envJson, late)
+	assert.True(s.T(), errors.As(err, &expired))
+	_, err = registry.Decode(envJson, late)
+	assert.True(s.T(), errors.As(err, &expired))
+}
+
+func (s *FreshnessSuite) TestForwardKeepsExp() {
+	se := s.envelope(time.Minute, "")
+	fwd, err := Forward(se, "relay")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), *se.AsEnvelope().Exp, *fwd.AsEnvelope().Exp)
+}
+
+func TestFreshnessSuite(t *testing.T) {
+	suite.Run(t, new(FreshnessSuite))
+}
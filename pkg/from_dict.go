@@ -0,0 +1,223 @@
+package c5
+
+//go:generate go run ../cmd/c5gen -package c5 -o envelope.go ../schema/envelope.json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// FromDictOption tightens the generated FromDict functions.
+type FromDictOption func(*FromDictOptions)
+
+// FromDictOptions is what the FromDictOption set, the generated code
+// passes it on to the nested types.
+type FromDictOptions struct {
+	RejectUnknown bool
+}
+
+// RejectUnknownFields fails fields the type does not declare with an
+// UnknownFieldError.
+func RejectUnknownFields() FromDictOption {
+	return func(o *FromDictOptions) {
+		o.RejectUnknown = true
+	}
+}
+
+func NewFromDictOptions(opts []FromDictOption) *FromDictOptions {
+	o := &FromDictOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// DecodeDict decodes the JSON object data for the FromDict functions, the
+// numbers stay json.Number so integers like nanosecond timestamps keep
+// all their digits.
+func DecodeDict(data []byte) (map[string]interface{}, error) {
+	dict := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&dict); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid character after top-level value")
+	}
+	return dict, nil
+}
+
+// dictType names the JSON type of v for error messages
+func dictType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// DictField returns the field key of data, a MissingFieldError if there
+// is none.
+func DictField(data map[string]interface{}, key string, path string) (interface{}, error) {
+	v, found := data[key]
+	if !found {
+		return nil, &MissingFieldError{Path: path + "/" + escapePointer(key)}
+	}
+	return v, nil
+}
+
+func DictString(v interface{}, path string) (string, error) {
+	str, ok := v.(string)
+	if !ok {
+		return "", &FieldTypeError{Path: path, Expected: "string", Actual: dictType(v)}
+	}
+	return str, nil
+}
+
+func DictBoolean(v interface{}, path string) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, &FieldTypeError{Path: path, Expected: "boolean", Actual: dictType(v)}
+	}
+	return b, nil
+}
+
+// DictNumber accepts any Go number and json.Number.
+func DictNumber(v interface{}, path string) (float64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if f, err := strconv.ParseFloat(string(v), 64); err == nil {
+			return f, nil
+		}
+	default:
+		val := reflect.ValueOf(v)
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(val.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(val.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			return val.Float(), nil
+		}
+	}
+	return 0, &FieldTypeError{Path: path, Expected: "number", Actual: dictType(v)}
+}
+
+// DictInteger accepts the numbers without fraction which fit an int64.
+func DictInteger(v interface{}, path string) (int64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(string(v), 64); err == nil {
+			return floatInteger(f, path)
+		}
+	default:
+		val := reflect.ValueOf(v)
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return val.Int(), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if val.Uint() <= math.MaxInt64 {
+				return int64(val.Uint()), nil
+			}
+			return 0, &FieldTypeError{Path: path, Expected: "integer", Actual: "number"}
+		case reflect.Float32, reflect.Float64:
+			return floatInteger(val.Float(), path)
+		}
+	}
+	return 0, &FieldTypeError{Path: path, Expected: "integer", Actual: dictType(v)}
+}
+
+func floatInteger(f float64, path string) (int64, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, &FieldTypeError{Path: path, Expected: "integer", Actual: "number"}
+	}
+	return int64(f), nil
+}
+
+// DictObject accepts maps with string keys.
+func DictObject(v interface{}, path string) (map[string]interface{}, error) {
+	if obj, ok := v.(map[string]interface{}); ok {
+		return obj, nil
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Map || val.Type().Key().Kind() != reflect.String {
+		return nil, &FieldTypeError{Path: path, Expected: "object", Actual: dictType(v)}
+	}
+	obj := make(map[string]interface{}, val.Len())
+	iter := val.MapRange()
+	for iter.Next() {
+		obj[iter.Key().String()] = iter.Value().Interface()
+	}
+	return obj, nil
+}
+
+// DictArray accepts any slice or array, like the []string ToDict returns.
+func DictArray(v interface{}, path string) ([]interface{}, error) {
+	if arr, ok := v.([]interface{}); ok {
+		return arr, nil
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, &FieldTypeError{Path: path, Expected: "array", Actual: dictType(v)}
+	}
+	arr := make([]interface{}, val.Len())
+	for idx := range arr {
+		arr[idx] = val.Index(idx).Interface()
+	}
+	return arr, nil
+}
+
+// DictKnown rejects the fields of data which are not in known, if asked to.
+func DictKnown(data map[string]interface{}, path string, o *FromDictOptions, known ...string) error {
+	if !o.RejectUnknown {
+		return nil
+	}
+	var unknown []string
+	for key := range data {
+		found := false
+		for _, k := range known {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &UnknownFieldError{Path: path + "/" + escapePointer(unknown[0])}
+}
@@ -0,0 +1,128 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FromDictSuite struct {
+	suite.Suite
+}
+
+func (s *FromDictSuite) envelope() map[string]interface{} {
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"data": map[string]interface{}{"name": "object", "note": nil},
+			"kind": "test",
+		},
+		"dst": []interface{}{"a", "b"},
+		"id":  "id",
+		"src": "test case",
+		"t":   json.Number("1624140000000"),
+		"ttl": 10,
+		"v":   "A",
+	}
+}
+
+func (s *FromDictSuite) TestValid() {
+	var env EnvelopeT
+	assert.NoError(s.T(), FromDictEnvelopeT(s.envelope(), &env, RejectUnknownFields()))
+	assert.Equal(s.T(), []string{"a", "b"}, env.Dst)
+	assert.Equal(s.T(), int64(1624140000000), env.T)
+	assert.Equal(s.T(), float64(10), env.TTL)
+	assert.Equal(s.T(), V_A, env.V)
+	assert.Nil(s.T(), env.Data.Data["note"])
+	assert.Nil(s.T(), env.Data.ToDict()["data"].(map[string]interface{})["note"])
+}
+
+func (s *FromDictSuite) TestMissingField() {
+	for _, tc := range []struct {
+		change func(map[string]interface{})
+		path   string
+	}{
+		{func(e map[string]interface{}) { delete(e, "id") }, "/id"},
+		{func(e map[string]interface{}) { delete(e, "v") }, "/v"},
+		{func(e map[string]interface{}) { delete(e["data"].(map[string]interface{}), "kind") }, "/data/kind"},
+		{func(e map[string]interface{}) { delete(e["data"].(map[string]interface{}), "data") }, "/data/data"},
+	} {
+		env := s.envelope()
+		tc.change(env)
+		var merr *MissingFieldError
+		err := FromDictEnvelopeT(env, &EnvelopeT{})
+		if assert.True(s.T(), errors.As(err, &merr), tc.path) {
+			assert.Equal(s.T(), tc.path, merr.Path)
+		}
+	}
+}
+
+func (s *FromDictSuite) TestFieldType() {
+	for _, tc := range []struct {
+		change   func(map[string]interface{})
+		path     string
+		expected string
+		actual   string
+	}{
+		{func(e map[string]interface{}) { e["id"] = 4 }, "/id", "string", "number"},
+		{func(e map[string]interface{}) { e["dst"] = []interface{}{"a", 1.5} }, "/dst/1", "string", "number"},
+		{func(e map[string]interface{}) { e["dst"] = "a" }, "/dst", "array", "string"},
+		{func(e map[string]interface{}) { e["t"] = "now" }, "/t", "integer", "string"},
+		{func(e map[string]interface{}) { e["ttl"] = nil }, "/ttl", "number", "null"},
+		{func(e map[string]interface{}) { e["data"] = []interface{}{} }, "/data", "object", "array"},
+		{func(e map[string]interface{}) { e["data"].(map[string]interface{})["kind"] = true }, "/data/kind", "string", "boolean"},
+	} {
+		env := s.envelope()
+		tc.change(env)
+		var terr *FieldTypeError
+		err := FromDictEnvelopeT(env, &EnvelopeT{})
+		if assert.True(s.T(), errors.As(err, &terr), tc.path) {
+			assert.Equal(s.T(), tc.path, terr.Path)
+			assert.Equal(s.T(), tc.expected, terr.Expected)
+			assert.Equal(s.T(), tc.actual, terr.Actual)
+		}
+	}
+	err := FromDictSampleY(map[string]interface{}{"y": "1"}, &SampleY{})
+	assert.EqualError(s.T(), err, "field '/y' expected number, got string")
+}
+
+func (s *FromDictSuite) TestInvalidV() {
+	env := s.envelope()
+	env["v"] = "Z"
+	var ferr *FieldError
+	assert.True(s.T(), errors.As(FromDictEnvelopeT(env, &EnvelopeT{}), &ferr))
+	assert.Equal(s.T(), "/v", ferr.Path)
+}
+
+func (s *FromDictSuite) TestRejectUnknownFields() {
+	env := s.envelope()
+	env["extra"] = 1
+	assert.NoError(s.T(), FromDictEnvelopeT(env, &EnvelopeT{}))
+	var uerr *UnknownFieldError
+	assert.True(s.T(), errors.As(FromDictEnvelopeT(env, &EnvelopeT{}, RejectUnknownFields()), &uerr))
+	assert.Equal(s.T(), "/extra", uerr.Path)
+
+	env = s.envelope()
+	env["data"].(map[string]interface{})["extra"] = 1
+	assert.True(s.T(), errors.As(FromDictEnvelopeT(env, &EnvelopeT{}, RejectUnknownFields()), &uerr))
+	assert.Equal(s.T(), "/data/extra", uerr.Path)
+
+	_, err := UnmarshalSampleNameDate([]byte(`{"date":"2021-05-20","name":"object","x":1}`), RejectUnknownFields())
+	assert.True(s.T(), errors.As(err, &uerr))
+	assert.Equal(s.T(), "/x", uerr.Path)
+}
+
+func (s *FromDictSuite) TestUnmarshalDoesNotPanic() {
+	for _, in := range []string{`{}`, `{"data":null}`, `{"data":{"data":1,"kind":"x"}}`, `{"dst":[null]}`} {
+		assert.NotPanics(s.T(), func() {
+			_, err := UnmarshalEnvelopeT([]byte(in))
+			assert.Error(s.T(), err, in)
+		})
+	}
+}
+
+func TestFromDictSuite(t *testing.T) {
+	suite.Run(t, new(FromDictSuite))
+}
@@ -0,0 +1,155 @@
+package c5
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	hashLib "hash"
+
+	"github.com/btcsuite/btcutil/base58"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+type HashAlgorithm string
+
+const (
+	SHA2_256    HashAlgorithm = "sha2-256"
+	SHA2_512    HashAlgorithm = "sha2-512"
+	SHA3_256    HashAlgorithm = "sha3-256"
+	BLAKE2B_256 HashAlgorithm = "blake2b-256"
+)
+
+// multihash codes, see https://github.com/multiformats/multicodec
+var multihashCodes = map[HashAlgorithm]uint64{
+	SHA2_256:    0x12,
+	SHA2_512:    0x13,
+	SHA3_256:    0x16,
+	BLAKE2B_256: 0xb220,
+}
+
+type DigestEncoding string
+
+const (
+	BASE58    DigestEncoding = "base58"
+	HEX       DigestEncoding = "hex"
+	BASE64URL DigestEncoding = "base64url"
+)
+
+// multibase prefixes, see https://github.com/multiformats/multibase
+var multibasePrefixes = map[DigestEncoding]byte{
+	BASE58:    'z',
+	HEX:       'f',
+	BASE64URL: 'u',
+}
+
+// HashSpec selects the algorithm and encoding of a digest. The zero value
+// is sha2-256 in plain base58, which is what envelope ids always used.
+// Every other combination is written as multibase encoded multihash so a
+// verifier can recompute it from the id alone. Tagged is not part of the
+// digest, it follows from the envelope version.
+type HashSpec struct {
+	Algorithm HashAlgorithm
+	Encoding  DigestEncoding
+	Tagged    bool
+}
+
+func (h HashSpec) algorithm() HashAlgorithm {
+	if h.Algorithm == "" {
+		return SHA2_256
+	}
+	return h.Algorithm
+}
+
+func (h HashSpec) encoding() DigestEncoding {
+	if h.Encoding == "" {
+		return BASE58
+	}
+	return h.Encoding
+}
+
+func (h HashSpec) isLegacy() bool {
+	return h.algorithm() == SHA2_256 && h.encoding() == BASE58
+}
+
+func (h HashSpec) newHash() (hashLib.Hash, error) {
+	if _, found := multibasePrefixes[h.encoding()]; !found {
+		return nil, &UnsupportedEncodingError{Encoding: string(h.encoding())}
+	}
+	switch h.algorithm() {
+	case SHA2_256:
+		return sha256.New(), nil
+	case SHA2_512:
+		return sha512.New(), nil
+	case SHA3_256:
+		return sha3.New256(), nil
+	case BLAKE2B_256:
+		return blake2b.New256(nil)
+	}
+	return nil, &UnsupportedAlgorithmError{Alg: string(h.algorithm())}
+}
+
+func (h HashSpec) encode(sum []byte) string {
+	if h.isLegacy() {
+		return base58.Encode(sum)
+	}
+	mh := make([]byte, 2*binary.MaxVarintLen64+len(sum))
+	n := binary.PutUvarint(mh, multihashCodes[h.algorithm()])
+	n += binary.PutUvarint(mh[n:], uint64(len(sum)))
+	mh = append(mh[:n], sum...)
+	var str string
+	switch h.encoding() {
+	case HEX:
+		str = hex.EncodeToString(mh)
+	case BASE64URL:
+		str = base64.RawURLEncoding.EncodeToString(mh)
+	default:
+		str = base58.Encode(mh)
+	}
+	return string(multibasePrefixes[h.encoding()]) + str
+}
+
+// ParseDigest returns the HashSpec a digest was written with. Anything
+// which is not a multibase encoded multihash is taken as plain base58
+// sha2-256, and so is anything which reads as a plain sha2-256 first: a
+// plain digest may well start with 'z', 'f' or 'u'.
+func ParseDigest(digest string) HashSpec {
+	if len(digest) < 2 || len(base58.Decode(digest)) == sha256.Size {
+		return HashSpec{}
+	}
+	var mh []byte
+	var err error
+	var encoding DigestEncoding
+	switch digest[0] {
+	case multibasePrefixes[BASE58]:
+		encoding = BASE58
+		mh = base58.Decode(digest[1:])
+	case multibasePrefixes[HEX]:
+		encoding = HEX
+		mh, err = hex.DecodeString(digest[1:])
+	case multibasePrefixes[BASE64URL]:
+		encoding = BASE64URL
+		mh, err = base64.RawURLEncoding.DecodeString(digest[1:])
+	default:
+		return HashSpec{}
+	}
+	if err != nil {
+		return HashSpec{}
+	}
+	code, n := binary.Uvarint(mh)
+	if n <= 0 {
+		return HashSpec{}
+	}
+	size, m := binary.Uvarint(mh[n:])
+	if m <= 0 || uint64(len(mh)-n-m) != size {
+		return HashSpec{}
+	}
+	for alg, c := range multihashCodes {
+		if c == code {
+			return HashSpec{Algorithm: alg, Encoding: encoding}
+		}
+	}
+	return HashSpec{}
+}
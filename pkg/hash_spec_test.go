@@ -0,0 +1,110 @@
+package c5
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HashSpecSuite struct {
+	suite.Suite
+}
+
+func (s *HashSpecSuite) digestOf(spec HashSpec, str string) string {
+	h, err := NewHashCollectorSpec(spec)
+	assert.NoError(s.T(), err)
+	SortKeys(str, h.Append)
+	return h.Digest()
+}
+
+func (s *HashSpecSuite) TestDefaultIsLegacy() {
+	assert.Equal(s.T(), "DzYqv3YaniBJWwqrNBn4534oTe4nL14TqcfVCguf9Yyv", s.digestOf(HashSpec{}, "1970-01-01T00:00:00.444Z"))
+	assert.Equal(s.T(), s.digestOf(HashSpec{}, "abc"), s.digestOf(HashSpec{Algorithm: SHA2_256, Encoding: BASE58}, "abc"))
+}
+
+func (s *HashSpecSuite) TestMultihashVectors() {
+	assert.Equal(s.T(), "f1220ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		s.digestOf(HashSpec{Encoding: HEX}, "abc"))
+	assert.Equal(s.T(), "f1340ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+		s.digestOf(HashSpec{Algorithm: SHA2_512, Encoding: HEX}, "abc"))
+	assert.Equal(s.T(), "f16203a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+		s.digestOf(HashSpec{Algorithm: SHA3_256, Encoding: HEX}, "abc"))
+	assert.Equal(s.T(), "fa0e40220bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319",
+		s.digestOf(HashSpec{Algorithm: BLAKE2B_256, Encoding: HEX}, "abc"))
+	assert.Equal(s.T(), "uEiC6eBa_jwHP6kFBQN5driIjsANho5YXepy0EP9h8gAVrQ",
+		s.digestOf(HashSpec{Encoding: BASE64URL}, "abc"))
+}
+
+func (s *HashSpecSuite) TestParseDigest() {
+	for _, spec := range []HashSpec{
+		{Algorithm: SHA2_512},
+		{Algorithm: SHA3_256, Encoding: HEX},
+		{Algorithm: BLAKE2B_256, Encoding: BASE64URL},
+		{Encoding: HEX},
+	} {
+		parsed := ParseDigest(s.digestOf(spec, "abc"))
+		assert.Equal(s.T(), spec.algorithm(), parsed.algorithm())
+		assert.Equal(s.T(), spec.encoding(), parsed.encoding())
+	}
+	assert.Equal(s.T(), HashSpec{}, ParseDigest(s.digestOf(HashSpec{}, "abc")))
+	assert.Equal(s.T(), HashSpec{}, ParseDigest("BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp"))
+}
+
+func (s *HashSpecSuite) TestLegacyDigestWithPrefix() {
+	// a plain digest starting with 'z' whose rest reads as a sha3-256 multihash
+	assert.Equal(s.T(), HashSpec{}, ParseDigest("zLZVNJ5PFNaYk1ZhgAz2RrbB7ZqhDBtBxgofKeq3tCY"))
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		T:    1,
+		Src:  "s",
+		Data: PayloadT1{Kind: "k", Data: map[string]interface{}{"n": 14568923}},
+	})
+	assert.Equal(s.T(), "1-zLZVNJ5PFNaYk1ZhgAz2RrbB7ZqhDBtBxgofKeq3tCY", se.AsEnvelope().ID)
+	assert.NoError(s.T(), se.Verify())
+	_, err := ParseSimpleEnvelope([]byte(*se.AsJson()))
+	assert.NoError(s.T(), err)
+}
+
+func (s *HashSpecSuite) TestEnvelopeRoundTrip() {
+	for _, spec := range []HashSpec{
+		{},
+		{Algorithm: SHA2_512, Encoding: BASE58},
+		{Algorithm: SHA3_256, Encoding: HEX},
+		{Algorithm: BLAKE2B_256, Encoding: BASE64URL},
+	} {
+		se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+			Src:            "test case",
+			Data:           PayloadT1{Kind: "test", Data: map[string]interface{}{"y": 4}},
+			TimeGenerator:  mtimer,
+			HashAlgorithm:  spec.Algorithm,
+			DigestEncoding: spec.Encoding,
+		})
+		js := *se.AsJson()
+		parsed, err := ParseSimpleEnvelope([]byte(js))
+		assert.NoError(s.T(), err, js)
+		b, err := parsed.Build()
+		assert.NoError(s.T(), err, js)
+		assert.True(s.T(), strings.HasSuffix(se.AsEnvelope().ID, "-"+b.DataHash()), js)
+		var merr *IDMismatchError
+		_, err = ParseSimpleEnvelope([]byte(strings.Replace(js, `"y":4`, `"y":5`, 1)))
+		assert.True(s.T(), errors.As(err, &merr), js)
+	}
+}
+
+func (s *HashSpecSuite) TestUnsupported() {
+	_, err := NewSimpleEnvelopeE(&SimpleEnvelopeProps{
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+		HashAlgorithm: "md5",
+	})
+	var aerr *UnsupportedAlgorithmError
+	assert.True(s.T(), errors.As(err, &aerr))
+	_, err = NewHashCollectorSpec(HashSpec{Encoding: "base32"})
+	var eerr *UnsupportedEncodingError
+	assert.True(s.T(), errors.As(err, &eerr))
+}
+
+func TestHashSpecSuite(t *testing.T) {
+	suite.Run(t, new(HashSpecSuite))
+}
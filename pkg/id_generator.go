@@ -0,0 +1,207 @@
+package c5
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// IDInput is what an IDGenerator may derive the id of an envelope from.
+type IDInput struct {
+	T      int64          // the timestamp of the envelope
+	Tr     TimeResolution // the unit of T
+	Src    string
+	Kind   string   // data.kind
+	Digest string   // the content hash of data.data
+	Spec   HashSpec // the HashSpec Digest was made with
+}
+
+// IDGenerator makes the id of an envelope whose ID is not set. A
+// Verifiable generator derives the id from the IDInput alone, so a
+// receiver recomputes it from the envelope and rejects a mismatch; the
+// ids of the others are accepted as they are.
+type IDGenerator interface {
+	NewID(in IDInput) (string, error)
+	Verifiable() bool
+}
+
+// ContentHashID makes "<t>-<digest>", the default.
+type ContentHashID struct{}
+
+func (ContentHashID) NewID(in IDInput) (string, error) {
+	return contentID(in.T, in.Digest), nil
+}
+
+func (ContentHashID) Verifiable() bool {
+	return true
+}
+
+// PureHashID makes "<digest>", envelopes with the same data share the id
+// whenever they are sent.
+type PureHashID struct{}
+
+func (PureHashID) NewID(in IDInput) (string, error) {
+	return in.Digest, nil
+}
+
+func (PureHashID) Verifiable() bool {
+	return true
+}
+
+// SourceKindHashID makes "<t>-<digest>" with a digest over src, kind and
+// the content hash, the same data sent by another source or as another
+// kind gets another id.
+type SourceKindHashID struct{}
+
+func (SourceKindHashID) NewID(in IDInput) (string, error) {
+	hash, err := in.Spec.newHash()
+	if err != nil {
+		return "", err
+	}
+	// the length prefixes keep "ab"+"c" apart from "a"+"bc"
+	var size [binary.MaxVarintLen64]byte
+	for _, part := range []string{in.Src, in.Kind, in.Digest} {
+		n := binary.PutUvarint(size[:], uint64(len(part)))
+		hash.Write(size[:n])
+		hash.Write([]byte(part))
+	}
+	return contentID(in.T, in.Spec.encode(hash.Sum(nil))), nil
+}
+
+func (SourceKindHashID) Verifiable() bool {
+	return true
+}
+
+// ULID makes a ULID of the time of the envelope and 80 random bits, the
+// ids sort by time. Rand defaults to crypto/rand.
+type ULID struct {
+	Rand io.Reader
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (g ULID) NewID(in IDInput) (string, error) {
+	var id [16]byte
+	putMillis(id[:6], in)
+	if _, err := io.ReadFull(randOf(g.Rand), id[6:]); err != nil {
+		return "", err
+	}
+	// 128 bits are 26 characters of 5 bits, the first one holds 3
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+func (ULID) Verifiable() bool {
+	return false
+}
+
+// UUIDv7 makes a RFC 9562 version 7 UUID of the time of the envelope and
+// 74 random bits, the ids sort by time. Rand defaults to crypto/rand.
+type UUIDv7 struct {
+	Rand io.Reader
+}
+
+func (g UUIDv7) NewID(in IDInput) (string, error) {
+	var id [16]byte
+	putMillis(id[:6], in)
+	if _, err := io.ReadFull(randOf(g.Rand), id[6:]); err != nil {
+		return "", err
+	}
+	id[6] = 0x70 | id[6]&0x0f
+	id[8] = 0x80 | id[8]&0x3f
+	str := hex.EncodeToString(id[:])
+	return strings.Join([]string{str[:8], str[8:12], str[12:16], str[16:20], str[20:]}, "-"), nil
+}
+
+func (UUIDv7) Verifiable() bool {
+	return false
+}
+
+// putMillis writes the time of in as 48 bit milliseconds since 1970
+func putMillis(b []byte, in IDInput) {
+	ms := uint64(in.Tr.Time(in.T).UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+func randOf(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+	return r
+}
+
+// idGeneratorsOf returns the generators which make ids shaped like id, a
+// parsed envelope does not tell which one made it. "<t>-<digest>" is a
+// ContentHashID or a SourceKindHashID, a bare digest a PureHashID.
+func idGeneratorsOf(id string) []IDGenerator {
+	switch {
+	case isULID(id):
+		return []IDGenerator{ULID{}}
+	case isUUIDv7(id):
+		return []IDGenerator{UUIDv7{}}
+	case idDigest(id) == id:
+		return []IDGenerator{PureHashID{}}
+	}
+	return []IDGenerator{ContentHashID{}, SourceKindHashID{}}
+}
+
+// isULID reports an id in the form ULID makes it.
+func isULID(id string) bool {
+	if len(id) != 26 || id[0] > '7' {
+		return false
+	}
+	for _, c := range id {
+		if !strings.ContainsRune(crockford, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUIDv7 reports an id in the form UUIDv7 makes it.
+func isUUIDv7(id string) bool {
+	if len(id) != 36 || id[14] != '7' || !strings.ContainsRune("89ab", rune(id[19])) {
+		return false
+	}
+	for i, c := range id {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdef", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// idDigest returns the digest part of an id, what follows the "<t>-" of
+// a timestamped id or the whole id. Base64url digests may contain "-"
+// but never start with a digit, they carry a 'u' prefix.
+func idDigest(id string) string {
+	idx := strings.Index(id, "-")
+	if idx <= 0 {
+		return id
+	}
+	for _, c := range id[:idx] {
+		if c < '0' || c > '9' {
+			return id
+		}
+	}
+	return id[idx+1:]
+}
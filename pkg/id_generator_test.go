@@ -0,0 +1,225 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IDGeneratorSuite struct {
+	suite.Suite
+}
+
+// zeros is a Rand which makes the random bits of ULID and UUIDv7 known
+func zeros() *bytes.Reader {
+	return bytes.NewReader(make([]byte, 16))
+}
+
+func (s *IDGeneratorSuite) envelope(g IDGenerator, src string) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           src,
+		Dst:           []string{},
+		T:             int64(1624140000000),
+		IDGenerator:   g,
+		TimeGenerator: mtimer,
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"date": "2021-05-20", "name": "object"}},
+	})
+}
+
+func (s *IDGeneratorSuite) TestIDs() {
+	for g, expected := range map[IDGenerator]string{
+		nil:                   "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp",
+		ContentHashID{}:       "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp",
+		PureHashID{}:          "BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp",
+		ULID{Rand: zeros()}:   "01F8K4KAR00000000000000000",
+		UUIDv7{Rand: zeros()}: "017a2649-ab00-7000-8000-000000000000",
+	} {
+		assert.Equal(s.T(), expected, s.envelope(g, "test case").AsEnvelope().ID, "%T", g)
+	}
+}
+
+func (s *IDGeneratorSuite) TestSourceKindHash() {
+	id := s.envelope(SourceKindHashID{}, "test case").AsEnvelope().ID
+	assert.True(s.T(), strings.HasPrefix(id, "1624140000000-"), id)
+	assert.NotEqual(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", id)
+	assert.Equal(s.T(), id, s.envelope(SourceKindHashID{}, "test case").AsEnvelope().ID)
+	assert.NotEqual(s.T(), id, s.envelope(SourceKindHashID{}, "other").AsEnvelope().ID)
+	assert.NotEqual(s.T(), id, s.envelope(SourceKindHashID{}, "test cas").AsEnvelope().ID)
+
+	envJson := *s.envelope(SourceKindHashID{}, "test case").AsJson()
+	_, err := ParseSimpleEnvelope([]byte(envJson), WithIDGenerator(SourceKindHashID{}))
+	assert.NoError(s.T(), err)
+	var mismatch *IDMismatchError
+	_, err = ParseSimpleEnvelope([]byte(strings.Replace(envJson, `"src":"test case"`, `"src":"other"`, 1)),
+		WithIDGenerator(SourceKindHashID{}))
+	assert.True(s.T(), errors.As(err, &mismatch), err)
+	_, err = ParseSimpleEnvelope([]byte(strings.Replace(envJson, `"kind":"test"`, `"kind":"other"`, 1)),
+		WithIDGenerator(SourceKindHashID{}))
+	assert.True(s.T(), errors.As(err, &mismatch), err)
+	// the id is no content id but may be one of SourceKindHashID
+	_, err = ParseSimpleEnvelope([]byte(envJson))
+	assert.NoError(s.T(), err)
+	_, err = ParseSimpleEnvelope([]byte(envJson), WithIDGenerator(ContentHashID{}))
+	assert.True(s.T(), errors.As(err, &mismatch), err)
+}
+
+func (s *IDGeneratorSuite) TestVerifiable() {
+	for _, g := range []IDGenerator{ContentHashID{}, PureHashID{}, SourceKindHashID{}} {
+		assert.True(s.T(), g.Verifiable(), "%T", g)
+		se := s.envelope(g, "test case")
+		assert.NoError(s.T(), se.Verify(), "%T", g)
+		envJson := *se.AsJson()
+		parsed, err := ParseSimpleEnvelope([]byte(envJson), WithIDGenerator(g))
+		assert.NoError(s.T(), err, "%T", g)
+		assert.NoError(s.T(), parsed.Verify(), "%T", g)
+		assert.NoError(s.T(), VerifyEnvelopeT(se.AsEnvelope(), WithIDGenerator(g)), "%T", g)
+		typed, err := ParseEnvelope[SampleNameDate]([]byte(envJson), WithIDGenerator(g))
+		assert.NoError(s.T(), err, "%T", g)
+		assert.NoError(s.T(), typed.Verify(WithIDGenerator(g)), "%T", g)
+
+		var mismatch *IDMismatchError
+		tampered := strings.Replace(envJson, "2021-05-20", "2021-05-21", 1)
+		_, err = ParseSimpleEnvelope([]byte(tampered), WithIDGenerator(g))
+		assert.True(s.T(), errors.As(err, &mismatch), "%T %v", g, err)
+		_, err = NewKindRegistry().Parse([]byte(tampered), WithIDGenerator(g))
+		assert.True(s.T(), errors.As(err, &mismatch), "%T %v", g, err)
+	}
+}
+
+func (s *IDGeneratorSuite) TestNotVerifiable() {
+	for _, g := range []IDGenerator{ULID{}, UUIDv7{}} {
+		assert.False(s.T(), g.Verifiable(), "%T", g)
+		se := s.envelope(g, "test case")
+		assert.NotEqual(s.T(), se.AsEnvelope().ID, s.envelope(g, "test case").AsEnvelope().ID, "%T", g)
+		assert.NoError(s.T(), se.Verify(), "%T", g)
+		tampered := strings.Replace(*se.AsJson(), "2021-05-20", "2021-05-21", 1)
+		parsed, err := ParseSimpleEnvelope([]byte(tampered), WithIDGenerator(g))
+		assert.NoError(s.T(), err, "%T", g)
+		assert.Equal(s.T(), se.AsEnvelope().ID, parsed.AsEnvelope().ID, "%T", g)
+
+		var mismatch *IDMismatchError
+		_, err = ParseSimpleEnvelope([]byte(*se.AsJson()), WithIDGenerator(ContentHashID{}))
+		assert.True(s.T(), errors.As(err, &mismatch), "%T %v", g, err)
+	}
+}
+
+func (s *IDGeneratorSuite) TestParseTellsGenerator() {
+	registry := NewKindRegistry()
+	assert.NoError(s.T(), RegisterKind[SampleNameDate](registry, "test"))
+	for _, g := range []IDGenerator{ContentHashID{}, PureHashID{}, SourceKindHashID{}, ULID{}, UUIDv7{}} {
+		envJson := *s.envelope(g, "test case").AsJson()
+		parsed, err := ParseSimpleEnvelope([]byte(envJson))
+		assert.NoError(s.T(), err, "%T", g)
+		assert.NoError(s.T(), parsed.Verify(), "%T", g)
+		_, err = registry.Parse([]byte(envJson))
+		assert.NoError(s.T(), err, "%T", g)
+		typed, err := ParseEnvelope[SampleNameDate]([]byte(envJson))
+		assert.NoError(s.T(), err, "%T", g)
+		assert.NoError(s.T(), typed.Verify(), "%T", g)
+		assert.NoError(s.T(), VerifyEnvelopeT(parsed.AsEnvelope()), "%T", g)
+
+		tampered := strings.Replace(envJson, "2021-05-20", "2021-05-21", 1)
+		_, err = ParseSimpleEnvelope([]byte(tampered))
+		var mismatch *IDMismatchError
+		assert.Equal(s.T(), g.Verifiable(), errors.As(err, &mismatch), "%T %v", g, err)
+	}
+}
+
+func (s *IDGeneratorSuite) TestIDShapes() {
+	for id, expected := range map[string][]IDGenerator{
+		"01F8K4KAR00000000000000000":                                 {ULID{}},
+		"017a2649-ab00-7000-8000-000000000000":                       {UUIDv7{}},
+		"BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp":               {PureHashID{}},
+		"1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp": {ContentHashID{}, SourceKindHashID{}},
+		// too short for a ULID, a v4 and no v7 UUID
+		"01F8K4KAR0000000000000000":            {PureHashID{}},
+		"017a2649-ab00-4000-8000-000000000000": {PureHashID{}},
+	} {
+		assert.Equal(s.T(), expected, idGeneratorsOf(id), id)
+	}
+}
+
+func (s *IDGeneratorSuite) TestRandomIDOnce() {
+	key := ed25519.NewKeyFromSeed([]byte("c5-envelope-test-seed-0123456789"))
+	keys := StaticKeyResolver{"k": key.Public().(ed25519.PublicKey)}
+	for _, g := range []IDGenerator{ULID{}, UUIDv7{}} {
+		se := s.envelope(g, "test case")
+		id := se.AsEnvelope().ID
+		signed, err := se.Sign(&Ed25519Signer{KeyID: "k", PrivateKey: key})
+		assert.NoError(s.T(), err, "%T", g)
+		assert.Equal(s.T(), id, signed.Envelope.ID, "%T", g)
+		assert.NoError(s.T(), signed.Verify(keys), "%T", g)
+
+		se = s.envelope(g, "test case")
+		var written bytes.Buffer
+		_, err = se.WriteTo(&written)
+		assert.NoError(s.T(), err, "%T", g)
+		assert.Equal(s.T(), written.String(), *se.AsJson(), "%T", g)
+		var again bytes.Buffer
+		againC := NewJsonWriterCollector(&again, nil)
+		assert.NoError(s.T(), se.Collect(againC), "%T", g)
+		assert.NoError(s.T(), againC.Flush(), "%T", g)
+		assert.Contains(s.T(), again.String(), se.AsEnvelope().ID, "%T", g)
+	}
+}
+
+func (s *IDGeneratorSuite) TestTimeOrdered() {
+	for _, g := range []IDGenerator{ULID{}, UUIDv7{}} {
+		earlier := s.envelope(g, "test case").AsEnvelope().ID
+		later := NewSimpleEnvelope(&SimpleEnvelopeProps{
+			T:           int64(1624140000001),
+			IDGenerator: g,
+			Data:        PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+		}).AsEnvelope().ID
+		assert.Less(s.T(), earlier, later, "%T", g)
+	}
+	// the time is the one of the envelope in any resolution
+	id := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		T:              int64(1624140000000123),
+		TimeResolution: TimeResolution_Us,
+		IDGenerator:    UUIDv7{Rand: zeros()},
+		Data:           PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+	}).AsEnvelope().ID
+	assert.Equal(s.T(), "017a2649-ab00-7000-8000-000000000000", id)
+}
+
+func (s *IDGeneratorSuite) TestDigestWithDash() {
+	for _, g := range []IDGenerator{ContentHashID{}, PureHashID{}, SourceKindHashID{}} {
+		for i := 0; i < 32; i++ {
+			se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+				Src:            "test case",
+				Dst:            []string{},
+				DigestEncoding: BASE64URL,
+				IDGenerator:    g,
+				TimeGenerator:  mtimer,
+				Data:           PayloadT1{Kind: "test", Data: map[string]interface{}{"i": i}},
+			})
+			_, err := ParseSimpleEnvelope([]byte(*se.AsJson()), WithIDGenerator(g))
+			assert.NoError(s.T(), err, "%T %v", g, se.AsEnvelope().ID)
+		}
+	}
+}
+
+func (s *IDGeneratorSuite) TestForwardKeepsID() {
+	se := s.envelope(ULID{}, "test case")
+	fwd, err := Forward(se, "relay")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), se.AsEnvelope().ID, fwd.AsEnvelope().ID)
+}
+
+func (s *IDGeneratorSuite) TestIDDigest() {
+	assert.Equal(s.T(), "zabc", idDigest("1624140000000-zabc"))
+	assert.Equal(s.T(), "uab-c", idDigest("1624140000000-uab-c"))
+	assert.Equal(s.T(), "uab-c", idDigest("uab-c"))
+	assert.Equal(s.T(), "Bb1", idDigest("Bb1"))
+	assert.Equal(s.T(), "-abc", idDigest("-abc"))
+}
+
+func TestIDGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(IDGeneratorSuite))
+}
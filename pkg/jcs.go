@@ -0,0 +1,239 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Utf16Less orders keys by their UTF-16 code units as RFC 8785 requires.
+// It only differs from byte order for characters beyond the BMP.
+func Utf16Less(a, b string) bool {
+	ua := utf16.Encode([]rune(a))
+	ub := utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// JcsCollector writes the SortKeys stream as RFC 8785 JSON Canonicalization
+// Scheme. It has to be fed by SortKeysOrderedE with Utf16Less.
+type JcsCollector struct {
+	output OutputFN
+	// first is true as long as the current array or object is empty
+	first   []bool
+	keyDone bool
+}
+
+func NewJcsCollector(o OutputFN) *JcsCollector {
+	return &JcsCollector{
+		output: o,
+		first:  []bool{true},
+	}
+}
+
+func (j *JcsCollector) separator() {
+	if j.keyDone {
+		j.keyDone = false
+		return
+	}
+	if !j.first[len(j.first)-1] {
+		j.output(",")
+	}
+	j.first[len(j.first)-1] = false
+}
+
+func (j *JcsCollector) Append(sVal SVal) {
+	if err := j.AppendE(sVal); err != nil {
+		panic(err)
+	}
+}
+
+func (j *JcsCollector) AppendE(sVal SVal) error {
+	switch sVal.outState {
+	case ARRAY_START:
+		j.separator()
+		j.output("[")
+		j.first = append(j.first, true)
+		return nil
+	case OBJECT_START:
+		j.separator()
+		j.output("{")
+		j.first = append(j.first, true)
+		return nil
+	case ARRAY_END:
+		j.first = j.first[:len(j.first)-1]
+		j.output("]")
+		return nil
+	case OBJECT_END:
+		j.first = j.first[:len(j.first)-1]
+		j.output("}")
+		return nil
+	}
+	if sVal.val == nil {
+		// attribute, which might be the empty string
+		key, err := jcsString(sVal.attribute)
+		if err != nil {
+			return &UnmarshalableValueError{Path: sVal.path, Err: err}
+		}
+		j.separator()
+		j.output(key + ":")
+		j.keyDone = true
+		return nil
+	}
+	return j.appendValue(sVal)
+}
+
+func (j *JcsCollector) appendValue(sVal SVal) error {
+	var str string
+	var err error
+	switch v := sVal.val.AsValue().(type) {
+	case nil:
+		str = "null"
+	case bool:
+		str = strconv.FormatBool(v)
+	case string:
+		str, err = jcsString(v)
+	case json.Number:
+		var f float64
+		f, err = strconv.ParseFloat(string(v), 64)
+		if err == nil {
+			str, err = jcsNumber(f)
+		}
+	case time.Time:
+		var b []byte
+		b, err = json.Marshal(v)
+		str = string(b)
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			str, err = jcsNumber(float64(rv.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			str, err = jcsNumber(float64(rv.Uint()))
+		case reflect.Float32, reflect.Float64:
+			str, err = jcsNumber(rv.Float())
+		case reflect.String:
+			str, err = jcsString(rv.String())
+		case reflect.Bool:
+			str = strconv.FormatBool(rv.Bool())
+		default:
+			// canonicalize whatever encoding/json makes of it
+			var b []byte
+			b, err = json.Marshal(v)
+			if err != nil {
+				break
+			}
+			var decoded interface{}
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+			if err = dec.Decode(&decoded); err != nil {
+				break
+			}
+			return SortKeysOrderedE(decoded, j.AppendE, Utf16Less, sVal.path)
+		}
+	}
+	if err != nil {
+		return &UnmarshalableValueError{Path: sVal.path, Err: err}
+	}
+	j.separator()
+	j.output(str)
+	return nil
+}
+
+// jcsNumber serializes like ECMAScript's Number.prototype.toString, which is
+// what encoding/json implements for float64 as well.
+func jcsNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("unsupported number:%v", f)
+	}
+	if f == 0 {
+		// also -0
+		return "0", nil
+	}
+	b, err := json.Marshal(f)
+	return string(b), err
+}
+
+const hexDigits = "0123456789abcdef"
+
+func jcsString(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", fmt.Errorf("invalid utf-8 in string:%q", s)
+	}
+	var out strings.Builder
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				out.WriteString(`\u00`)
+				out.WriteByte(hexDigits[r>>4])
+				out.WriteByte(hexDigits[r&0xf])
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+	return out.String(), nil
+}
+
+// Jcs renders e as RFC 8785 canonical JSON.
+func Jcs(e interface{}) ([]byte, error) {
+	var out strings.Builder
+	jcsC := NewJcsCollector(func(str string) {
+		out.WriteString(str)
+	})
+	if err := SortKeysOrderedE(e, jcsC.AppendE, Utf16Less); err != nil {
+		return nil, err
+	}
+	return []byte(out.String()), nil
+}
+
+func (s *SimpleEnvelope) AsJcs() *string {
+	str, err := s.AsJcsE()
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+// AsJcsE renders the whole envelope as RFC 8785 canonical JSON, which
+// third party JCS implementations reproduce byte by byte.
+func (s *SimpleEnvelope) AsJcsE() (*string, error) {
+	env, err := s.AsEnvelopeE()
+	if err != nil {
+		return nil, err
+	}
+	b, err := Jcs(*env)
+	if err != nil {
+		return nil, err
+	}
+	str := string(b)
+	return &str, nil
+}
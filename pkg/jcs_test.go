@@ -0,0 +1,121 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JcsSuite struct {
+	suite.Suite
+}
+
+func (s *JcsSuite) jcsOf(in string) string {
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader([]byte(in)))
+	dec.UseNumber()
+	assert.NoError(s.T(), dec.Decode(&decoded))
+	out, err := Jcs(decoded)
+	assert.NoError(s.T(), err)
+	return string(out)
+}
+
+// RFC 8785 section 3.2.2
+func (s *JcsSuite) TestRfcSample() {
+	in := `{
+  "numbers": [333333333.33333329, 1E30, 4.50,
+              2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`
+	assert.Equal(s.T(),
+		`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		s.jcsOf(in))
+}
+
+// RFC 8785 section 3.2.3
+func (s *JcsSuite) TestRfcSorting() {
+	in := `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`
+	assert.Equal(s.T(),
+		"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\","+
+			"\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\","+
+			"\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		s.jcsOf(in))
+}
+
+// RFC 8785 appendix B
+func (s *JcsSuite) TestRfcNumbers() {
+	for _, tc := range []struct {
+		bits uint64
+		out  string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		out, err := Jcs(math.Float64frombits(tc.bits))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), tc.out, string(out), "%016x", tc.bits)
+	}
+	for _, bits := range []uint64{0x7fffffffffffffff, 0x7ff0000000000000} {
+		_, err := Jcs(math.Float64frombits(bits))
+		var uerr *UnmarshalableValueError
+		assert.True(s.T(), errors.As(err, &uerr))
+	}
+}
+
+func (s *JcsSuite) TestNoHtmlEscaping() {
+	out, err := Jcs(map[string]interface{}{"": "<&>\u2028", "a": []int{}, "b": struct{}{}})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "{\"\":\"<&>\u2028\",\"a\":[],\"b\":{}}", string(out))
+}
+
+func (s *JcsSuite) TestEnvelope() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"y": 1.5, "x": "<"}},
+		Dst:           []string{},
+		TimeGenerator: mtimer,
+	})
+	assert.Equal(s.T(),
+		`{"data":{"data":{"x":"<","y":1.5},"kind":"test"},"dst":[],"id":"`+se.AsEnvelope().ID+`","src":"test case","t":1624140000000,"ttl":10,"v":"A"}`,
+		*se.AsJcs())
+}
+
+func TestJcsSuite(t *testing.T) {
+	suite.Run(t, new(JcsSuite))
+}
@@ -0,0 +1,131 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	hashLib "hash"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const HMAC_SHA256 = "HS256"
+
+var ErrMissingMac = errors.New("envelope carries no mac")
+
+type Mac struct {
+	Alg   string `json:"alg"`
+	KeyID string `json:"kid"`
+	Mac   string `json:"mac"`
+}
+
+// MacKeyResolver returns the shared secret named by keyID.
+type MacKeyResolver interface {
+	ResolveMacKey(keyID string) ([]byte, error)
+}
+
+type StaticMacKeyResolver map[string][]byte
+
+func (r StaticMacKeyResolver) ResolveMacKey(keyID string) ([]byte, error) {
+	key, found := r[keyID]
+	if !found {
+		return nil, &UnknownKeyError{KeyID: keyID}
+	}
+	return key, nil
+}
+
+// MacCollector computes a HMAC-SHA256 over the compact canonical JSON of
+// the tokens, the bytes CanonicalJson renders for the envelope without
+// its mac.
+type MacCollector struct {
+	mac   hashLib.Hash
+	jsonC *JsonCollector
+}
+
+func NewMacCollector(key []byte) *MacCollector {
+	mac := hmac.New(sha256.New, key)
+	return &MacCollector{
+		mac:   mac,
+		jsonC: NewJsonWriterCollector(mac, nil),
+	}
+}
+
+func (c *MacCollector) AppendE(sval SVal) error {
+	return c.jsonC.AppendE(sval)
+}
+
+// Sum returns the mac once the walk is done.
+func (c *MacCollector) Sum() ([]byte, error) {
+	if err := c.jsonC.Flush(); err != nil {
+		return nil, err
+	}
+	return c.mac.Sum(nil), nil
+}
+
+func macEnvelopeT(env *EnvelopeT, key []byte) ([]byte, error) {
+	macC := NewMacCollector(key)
+	if err := SortKeysE(*env, macC.AppendE); err != nil {
+		return nil, err
+	}
+	return macC.Sum()
+}
+
+// MacEnvelopeT computes the mac of the whole envelope.
+func MacEnvelopeT(env *EnvelopeT, keyID string, key []byte) (*Mac, error) {
+	sum, err := macEnvelopeT(env, key)
+	if err != nil {
+		return nil, err
+	}
+	return newMac(keyID, sum), nil
+}
+
+func newMac(keyID string, sum []byte) *Mac {
+	return &Mac{
+		Alg:   HMAC_SHA256,
+		KeyID: keyID,
+		Mac:   base58.Encode(sum),
+	}
+}
+
+// VerifyMac checks the "mac" member of an envelope in its JSON form and
+// returns the decoded envelope. The content id is not checked, use
+// VerifyEnvelopeT for that.
+func VerifyMac(b []byte, keys MacKeyResolver) (*EnvelopeT, error) {
+	env, err := UnmarshalEnvelopeT(b)
+	if err != nil {
+		return nil, err
+	}
+	raw := struct {
+		Mac  *Mac `json:"mac"`
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if raw.Mac == nil {
+		return nil, ErrMissingMac
+	}
+	if raw.Mac.Alg != HMAC_SHA256 {
+		return nil, &UnsupportedAlgorithmError{Alg: raw.Mac.Alg}
+	}
+	key, err := keys.ResolveMacKey(raw.Mac.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	macEnv := *env
+	macEnv.Data.Data = raw.Data.Data
+	sum, err := macEnvelopeT(&macEnv, key)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(sum, base58.Decode(raw.Mac.Mac)) {
+		return nil, &InvalidMacError{KeyID: raw.Mac.KeyID}
+	}
+	return env, nil
+}
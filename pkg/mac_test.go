@@ -0,0 +1,112 @@
+package c5
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MacSuite struct {
+	suite.Suite
+	keys StaticMacKeyResolver
+}
+
+func (s *MacSuite) SetupTest() {
+	s.keys = StaticMacKeyResolver{"hop-key": []byte("shared secret")}
+}
+
+func (s *MacSuite) newSimpleEnvelope(jsonProp *JsonProps) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{"name": "object", "y": 1624140000000},
+		},
+		Dst:           []string{},
+		TimeGenerator: mtimer,
+		JsonProp:      jsonProp,
+		MacKey:        []byte("shared secret"),
+		MacKeyID:      "hop-key",
+	})
+}
+
+func (s *MacSuite) TestMacCanonicalJson() {
+	se := s.newSimpleEnvelope(NewJsonProps(2, ""))
+	js := *se.AsJson()
+	id := se.AsEnvelope().ID
+
+	mac := hmac.New(sha256.New, []byte("shared secret"))
+	mac.Write([]byte(`{"data":{"data":{"name":"object","y":1624140000000},"kind":"test"},"dst":[],` +
+		`"id":"` + id + `","src":"test case","t":1624140000000,"ttl":10,"v":"A"}`))
+	expected := base58.Encode(mac.Sum(nil))
+	assert.Equal(s.T(), &Mac{Alg: HMAC_SHA256, KeyID: "hop-key", Mac: expected}, se.Mac)
+	assert.Contains(s.T(), js, `"mac": {`)
+	assert.Contains(s.T(), js, `"mac": "`+expected+`"`)
+}
+
+func (s *MacSuite) TestMacSeesBoundaries() {
+	mac := func(dst []string, data map[string]interface{}) *Mac {
+		se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+			ID:            "fixed",
+			Src:           "test case",
+			Dst:           dst,
+			Data:          PayloadT1{Kind: "test", Data: data},
+			TimeGenerator: mtimer,
+			MacKey:        []byte("shared secret"),
+		})
+		se.AsJson()
+		return se.Mac
+	}
+	data := map[string]interface{}{"a": "b"}
+	assert.NotEqual(s.T(), mac([]string{"a", "b"}, data), mac([]string{"ab"}, data))
+	assert.NotEqual(s.T(), mac([]string{}, data), mac([]string{}, map[string]interface{}{"ab": ""}))
+}
+
+func (s *MacSuite) TestVerifyRoundTrip() {
+	js := []byte(*s.newSimpleEnvelope(nil).AsJson())
+	env, err := VerifyMac(js, s.keys)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "test", env.Data.Kind)
+	_, err = ParseSimpleEnvelope(js)
+	assert.NoError(s.T(), err)
+}
+
+func (s *MacSuite) TestVerifyIndented() {
+	_, err := VerifyMac([]byte(*s.newSimpleEnvelope(NewJsonProps(2, "")).AsJson()), s.keys)
+	assert.NoError(s.T(), err)
+}
+
+func (s *MacSuite) TestVerifyTampered() {
+	js := *s.newSimpleEnvelope(nil).AsJson()
+	_, err := VerifyMac([]byte(strings.Replace(js, `"ttl":10`, `"ttl":11`, 1)), s.keys)
+	var merr *InvalidMacError
+	assert.True(s.T(), errors.As(err, &merr))
+	assert.Equal(s.T(), "hop-key", merr.KeyID)
+}
+
+func (s *MacSuite) TestVerifyUnknownKey() {
+	_, err := VerifyMac([]byte(*s.newSimpleEnvelope(nil).AsJson()), StaticMacKeyResolver{})
+	var kerr *UnknownKeyError
+	assert.True(s.T(), errors.As(err, &kerr))
+}
+
+func (s *MacSuite) TestVerifyMissingMac() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+		TimeGenerator: mtimer,
+	})
+	assert.Nil(s.T(), se.Mac)
+	_, err := VerifyMac([]byte(*se.AsJson()), s.keys)
+	assert.Equal(s.T(), ErrMissingMac, err)
+}
+
+func TestMacSuite(t *testing.T) {
+	suite.Run(t, new(MacSuite))
+}
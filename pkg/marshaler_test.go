@@ -0,0 +1,149 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MarshalerSuite struct {
+	suite.Suite
+}
+
+func (s *MarshalerSuite) sorted(e interface{}) (string, error) {
+	var out strings.Builder
+	col := NewJsonCollector(func(str string) {
+		out.WriteString(str)
+	}, nil)
+	err := SortKeysE(e, col.AppendE)
+	return out.String(), err
+}
+
+func (s *MarshalerSuite) hashOf(e interface{}) string {
+	h := NewHashCollector()
+	assert.NoError(s.T(), SortKeysE(e, h.AppendE))
+	return h.Digest()
+}
+
+type valMarshaler struct {
+	Z int
+	A int
+}
+
+func (v valMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"z":%d,"a":%d}`, v.Z, v.A)), nil
+}
+
+type ptrMarshaler struct {
+	N string
+}
+
+func (p *ptrMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`["` + p.N + `"]`), nil
+}
+
+type failMarshaler struct{}
+
+func (failMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("L%d", int(l))), nil
+}
+
+func (s *MarshalerSuite) TestMarshalJSONValueReceiver() {
+	out, err := s.sorted(map[string]interface{}{"m": valMarshaler{Z: 1, A: 2}})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"m":{"a":2,"z":1}}`, out)
+
+	out, err = s.sorted(map[string]interface{}{"m": &valMarshaler{Z: 3, A: 4}})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"m":{"a":4,"z":3}}`, out)
+}
+
+func (s *MarshalerSuite) TestMarshalJSONPointerReceiver() {
+	type Obj struct {
+		P ptrMarshaler  `json:"p"`
+		Q *ptrMarshaler `json:"q"`
+	}
+	// like encoding/json the pointer receiver applies to addressable
+	// values only, these are the ones behind a pointer
+	for e, expected := range map[interface{}]string{
+		Obj{P: ptrMarshaler{N: "x"}, Q: &ptrMarshaler{N: "y"}}:  `{"p":{"N":"x"},"q":["y"]}`,
+		&Obj{P: ptrMarshaler{N: "x"}, Q: &ptrMarshaler{N: "y"}}: `{"p":["x"],"q":["y"]}`,
+		ptrMarshaler{N: "z"}:  `{"N":"z"}`,
+		&ptrMarshaler{N: "z"}: `["z"]`,
+	} {
+		out, err := s.sorted(e)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), expected, out)
+		ref, err := json.Marshal(e)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), string(ref), out)
+	}
+}
+
+func (s *MarshalerSuite) TestMarshalJSONError() {
+	_, err := s.sorted(map[string]interface{}{"f": failMarshaler{}})
+	var uerr *UnmarshalableValueError
+	assert.True(s.T(), errors.As(err, &uerr))
+	assert.Equal(s.T(), "/f", uerr.Path)
+}
+
+func (s *MarshalerSuite) TestStdlibMarshalers() {
+	bi, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	out, err := s.sorted(map[string]interface{}{
+		"big": bi,
+		"ip":  net.ParseIP("10.0.0.1"),
+		"raw": json.RawMessage(`{"b":1,"a":[true]}`),
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"big":123456789012345678901234567890,"ip":"10.0.0.1","raw":{"a":[true],"b":1}}`, out)
+}
+
+func (s *MarshalerSuite) TestTextMarshaler() {
+	out, err := s.sorted(map[string]interface{}{"l": level(3)})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"l":"L3"}`, out)
+}
+
+func (s *MarshalerSuite) TestTextMarshalerMapKeys() {
+	out, err := s.sorted(map[level]int{2: 1, 1: 2})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"L1":2,"L2":1}`, out)
+}
+
+func (s *MarshalerSuite) TestHashMatchesDecodedJson() {
+	decoded := map[string]interface{}{"m": map[string]interface{}{"a": "x", "z": "y"}}
+	type Obj struct {
+		M level `json:"m"`
+	}
+	assert.Equal(s.T(),
+		s.hashOf(map[string]interface{}{"m": map[string]interface{}{"a": level(1)}}),
+		s.hashOf(map[string]interface{}{"m": map[string]interface{}{"a": "L1"}}))
+	assert.Equal(s.T(), s.hashOf(Obj{M: 7}), s.hashOf(map[string]interface{}{"m": "L7"}))
+	assert.NotEqual(s.T(), s.hashOf(decoded), s.hashOf(Obj{M: 7}))
+}
+
+func (s *MarshalerSuite) TestTimeUnchanged() {
+	tm := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	out, err := s.sorted(map[string]interface{}{"t": tm, "p": &tm})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"p":"2021-06-20T00:00:00Z","t":"2021-06-20T00:00:00Z"}`, out)
+	assert.Equal(s.T(), s.hashOf(map[string]interface{}{"t": tm}), s.hashOf(map[string]interface{}{"t": "2021-06-20T00:00:00Z"}))
+}
+
+func TestMarshalerSuite(t *testing.T) {
+	suite.Run(t, new(MarshalerSuite))
+}
@@ -0,0 +1,145 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// MemoSuite is meant to run with go test -race
+type MemoSuite struct {
+	suite.Suite
+}
+
+func (s *MemoSuite) props(walks *int) *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{
+				"name":    "object",
+				"counter": walkCounter{walks: walks},
+			},
+		},
+		Dst:           []string{"a"},
+		TimeGenerator: mtimer,
+		MacKey:        []byte("secret"),
+	}
+}
+
+func (s *MemoSuite) TestAsEnvelopeBeforeAsJson() {
+	walks := 0
+	ref := *NewSimpleEnvelope(s.props(&walks)).AsJson()
+
+	se := NewSimpleEnvelope(s.props(&walks))
+	env := se.AsEnvelope()
+	assert.Same(s.T(), env, se.AsEnvelope())
+	assert.Equal(s.T(), ref, *se.AsJson())
+	assert.Same(s.T(), se.AsJson(), se.AsJson())
+	assert.Equal(s.T(), 2, walks)
+}
+
+func (s *MemoSuite) TestConcurrentOnce() {
+	walks := 0
+	var mu sync.Mutex
+	props := s.props(&walks)
+	props.Data.(PayloadT1).Data["counter"] = lockedWalkCounter{mu: &mu, walks: &walks}
+	se := NewSimpleEnvelope(props)
+
+	var wg sync.WaitGroup
+	jsons := make([]string, 16)
+	envs := make([]*EnvelopeT, 16)
+	for i := range jsons {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				envs[i] = se.AsEnvelope()
+			}
+			jsons[i] = *se.AsJson()
+			envs[i] = se.AsEnvelope()
+		}(i)
+	}
+	wg.Wait()
+	for i := range jsons {
+		assert.Equal(s.T(), jsons[0], jsons[i])
+		assert.Same(s.T(), envs[0], envs[i])
+	}
+	assert.Equal(s.T(), 1, walks)
+}
+
+type lockedWalkCounter struct {
+	mu    *sync.Mutex
+	walks *int
+}
+
+func (w lockedWalkCounter) MarshalJSON() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	*w.walks++
+	return []byte(`{"b":1,"a":2}`), nil
+}
+
+func (s *MemoSuite) TestConcurrentMixed() {
+	walks := 0
+	var mu sync.Mutex
+	props := s.props(&walks)
+	props.Data.(PayloadT1).Data["counter"] = lockedWalkCounter{mu: &mu, walks: &walks}
+	ref := *NewSimpleEnvelope(props).AsJson()
+	se := NewSimpleEnvelope(props)
+	key := ed25519.NewKeyFromSeed([]byte("c5-envelope-test-seed-0123456789"))
+	signer := &Ed25519Signer{KeyID: "k1", PrivateKey: key}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch i % 5 {
+			case 0:
+				assert.Equal(s.T(), ref, *se.AsJson())
+			case 1:
+				var out bytes.Buffer
+				_, err := se.WriteTo(&out)
+				assert.NoError(s.T(), err)
+				assert.Equal(s.T(), ref, out.String())
+			case 2:
+				signed, err := se.Sign(signer)
+				assert.NoError(s.T(), err)
+				assert.NoError(s.T(), signed.Verify(StaticKeyResolver{"k1": key.Public().(ed25519.PublicKey)}))
+			case 3:
+				_, err := se.AsDataJsonE()
+				assert.NoError(s.T(), err)
+			case 4:
+				assert.NoError(s.T(), VerifyEnvelopeT(se.AsEnvelope()))
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.NotNil(s.T(), se.Mac)
+}
+
+func (s *MemoSuite) TestErrorRemembered() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		T: 4711,
+		Data: PayloadT1{Kind: "kind", Data: map[string]interface{}{
+			"ch": make(chan int),
+		}},
+	})
+	_, err := se.AsJsonE()
+	var uerr *UnmarshalableValueError
+	assert.True(s.T(), errors.As(err, &uerr))
+	_, err2 := se.AsEnvelopeE()
+	assert.Same(s.T(), err, err2)
+	_, err2 = se.WriteTo(&bytes.Buffer{})
+	assert.Same(s.T(), err, err2)
+}
+
+func TestMemoSuite(t *testing.T) {
+	suite.Run(t, new(MemoSuite))
+}
@@ -0,0 +1,88 @@
+package c5
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// Collector consumes the token stream of SortKeysE, JsonCollector,
+// HashCollector and JcsCollector are Collectors.
+type Collector interface {
+	AppendE(sval SVal) error
+}
+
+// FanOut passes every token to all of its collectors in the order they were
+// added. It is safe for concurrent use, a token is completely processed by
+// all collectors before the next one is.
+type FanOut struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewFanOut(collectors ...Collector) *FanOut {
+	return &FanOut{
+		collectors: append([]Collector{}, collectors...),
+	}
+}
+
+func (f *FanOut) Add(collectors ...Collector) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.collectors = append(f.collectors, collectors...)
+}
+
+func (f *FanOut) Append(sval SVal) {
+	if err := f.AppendE(sval); err != nil {
+		panic(err)
+	}
+}
+
+func (f *FanOut) AppendE(sval SVal) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.collectors {
+		if err := c.AppendE(sval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SizeCollector counts the bytes of the JSON the tokens render to without
+// keeping it.
+type SizeCollector struct {
+	mu      sync.Mutex
+	counter *countingWriter
+	jsonC   *JsonCollector
+}
+
+func NewSizeCollector(p *JsonProps) *SizeCollector {
+	counter := &countingWriter{w: io.Discard}
+	return &SizeCollector{
+		counter: counter,
+		jsonC:   NewJsonWriterCollector(counter, p),
+	}
+}
+
+func (c *SizeCollector) AppendE(sval SVal) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.jsonC.AppendE(sval)
+}
+
+func (c *SizeCollector) Size() (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.jsonC.Flush()
+	return c.counter.n, err
+}
+
+// isDataToken reports if sval belongs to /data/data, the part of the
+// envelope the content id hashes.
+func isDataToken(sval SVal) bool {
+	if sval.path == "/data/data" {
+		return sval.attribute == ""
+	}
+	return strings.HasPrefix(sval.path, "/data/data/")
+}
@@ -0,0 +1,185 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PipelineSuite struct {
+	suite.Suite
+}
+
+type countingCollector struct {
+	count int
+}
+
+func (c *countingCollector) AppendE(sval SVal) error {
+	c.count++
+	return nil
+}
+
+type failingCollector struct {
+	err error
+}
+
+func (c *failingCollector) AppendE(sval SVal) error {
+	return c.err
+}
+
+// walkCounter counts how often the walk marshals it
+type walkCounter struct {
+	walks *int
+}
+
+func (w walkCounter) MarshalJSON() ([]byte, error) {
+	*w.walks++
+	return []byte(`{"b":1,"a":2}`), nil
+}
+
+func (s *PipelineSuite) props(walks *int) *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{
+				"name":    "object",
+				"counter": walkCounter{walks: walks},
+			},
+		},
+		Dst:           []string{},
+		TimeGenerator: mtimer,
+	}
+}
+
+func (s *PipelineSuite) TestFanOut() {
+	data := map[string]interface{}{"b": []interface{}{1, "x"}, "a": nil}
+	var out strings.Builder
+	jsonC := NewJsonCollector(func(str string) {
+		out.WriteString(str)
+	}, nil)
+	hashC := NewHashCollector()
+	sizeC := NewSizeCollector(nil)
+	assert.NoError(s.T(), SortKeysE(data, NewFanOut(jsonC, hashC, sizeC).AppendE))
+
+	refHashC := NewHashCollector()
+	SortKeys(data, refHashC.Append)
+	assert.Equal(s.T(), `{"a":null,"b":[1,"x"]}`, out.String())
+	assert.Equal(s.T(), refHashC.Digest(), hashC.Digest())
+	size, err := sizeC.Size()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(out.Len()), size)
+}
+
+func (s *PipelineSuite) TestFanOutError() {
+	ferr := errors.New("stop")
+	counter := &countingCollector{}
+	err := SortKeysE(map[string]interface{}{"a": 1}, NewFanOut(&failingCollector{err: ferr}, counter).AppendE)
+	assert.True(s.T(), errors.Is(err, ferr))
+	assert.Equal(s.T(), 0, counter.count)
+}
+
+func (s *PipelineSuite) TestFanOutConcurrent() {
+	counter := &countingCollector{}
+	fanOut := NewFanOut(counter)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fanOut.Append(SVal{outState: NONE, val: JsonValType{j}})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(s.T(), 800, counter.count)
+}
+
+func (s *PipelineSuite) TestSingleWalk() {
+	walks := 0
+	key := ed25519.NewKeyFromSeed([]byte("c5-envelope-test-seed-0123456789"))
+	signer := &Ed25519Signer{KeyID: "k1", PrivateKey: key}
+	props := s.props(&walks)
+	props.MacKey = []byte("secret")
+	se := NewSimpleEnvelope(props)
+
+	sizeC := NewSizeCollector(nil)
+	sigC := NewSignatureCollector(signer)
+	assert.NoError(s.T(), se.Collect(sizeC, sigC))
+	envJson := *se.AsJson()
+	env := se.AsEnvelope()
+	assert.Equal(s.T(), 1, walks)
+
+	assert.NoError(s.T(), VerifyEnvelopeT(env))
+	_, err := VerifyMac([]byte(envJson), StaticMacKeyResolver{"": []byte("secret")})
+	assert.NoError(s.T(), err)
+	sig, err := sigC.Signature()
+	assert.NoError(s.T(), err)
+	signed := &SignedEnvelope{Envelope: *env, Signature: *sig}
+	assert.NoError(s.T(), signed.Verify(StaticKeyResolver{"k1": key.Public().(ed25519.PublicKey)}))
+
+	canonical, err := CanonicalJson(env)
+	assert.NoError(s.T(), err)
+	size, err := sizeC.Size()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(len(canonical)), size)
+}
+
+func (s *PipelineSuite) TestSignSingleWalk() {
+	walks := 0
+	key := ed25519.NewKeyFromSeed([]byte("c5-envelope-test-seed-0123456789"))
+	se := NewSimpleEnvelope(s.props(&walks))
+	signed, err := se.Sign(&Ed25519Signer{KeyID: "k1", PrivateKey: key})
+	assert.NoError(s.T(), err)
+	se.AsJson()
+	assert.Equal(s.T(), 1, walks)
+	assert.Equal(s.T(), se.AsEnvelope().ID, signed.Envelope.ID)
+	assert.NoError(s.T(), signed.Verify(StaticKeyResolver{"k1": key.Public().(ed25519.PublicKey)}))
+}
+
+func (s *PipelineSuite) TestCollectErrorIsNotKept() {
+	walks := 0
+	se := NewSimpleEnvelope(s.props(&walks))
+	ferr := errors.New("collector down")
+	err := se.Collect(&failingCollector{err: ferr})
+	assert.True(s.T(), errors.Is(err, ferr))
+	_, err = se.AsJsonE()
+	assert.NoError(s.T(), err)
+	_, err = se.AsEnvelopeE()
+	assert.NoError(s.T(), err)
+	_, err = se.Build()
+	assert.NoError(s.T(), err)
+	var out bytes.Buffer
+	_, err = se.WriteTo(&out)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), *se.AsJson(), out.String())
+	assert.Equal(s.T(), 1, walks)
+	assert.True(s.T(), errors.Is(se.Collect(&failingCollector{err: ferr}), ferr))
+}
+
+func (s *PipelineSuite) TestCollectMatchesSeparateWalks() {
+	walks := 0
+	props := s.props(&walks)
+	props.JsonProp = NewJsonProps(2, "")
+	props.V = V_B
+	se := NewSimpleEnvelope(props)
+	env := se.AsEnvelope()
+	assert.NoError(s.T(), verifyID(ContentHashID{}, env, props.Data.(PayloadT1).Data))
+	compact, err := CanonicalJson(env)
+	assert.NoError(s.T(), err)
+	var indented bytes.Buffer
+	assert.NoError(s.T(), json.Indent(&indented, compact, "", "  "))
+	assert.Equal(s.T(), indented.String(), *se.AsJson())
+}
+
+func TestPipelineSuite(t *testing.T) {
+	suite.Run(t, new(PipelineSuite))
+}
@@ -0,0 +1,154 @@
+package c5
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// KindOf names a versioned kind, "order" in version "2" is "order@2".
+func KindOf(kind string, version string) string {
+	if version == "" {
+		return kind
+	}
+	return kind + "@" + version
+}
+
+// KindRegistry maps payload kinds to Go types, so the data of an envelope
+// decodes by its kind. It is safe for concurrent use.
+type KindRegistry struct {
+	mu    sync.RWMutex
+	kinds map[string]reflect.Type
+	types map[reflect.Type]string
+}
+
+func NewKindRegistry() *KindRegistry {
+	return &KindRegistry{
+		kinds: map[string]reflect.Type{},
+		types: map[reflect.Type]string{},
+	}
+}
+
+// RegisterKind binds kind to T. Each kind and each type can be registered
+// once.
+func RegisterKind[T any](r *KindRegistry, kind string) error {
+	return r.register(kind, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// RegisterKindVersion binds the kind in version to T, see KindOf.
+func RegisterKindVersion[T any](r *KindRegistry, kind string, version string) error {
+	return RegisterKind[T](r, KindOf(kind, version))
+}
+
+func (r *KindRegistry) register(kind string, typ reflect.Type) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, found := r.kinds[kind]; found {
+		return &DuplicateKindError{Kind: kind}
+	}
+	if other, found := r.types[typ]; found {
+		return &DuplicateKindError{Kind: other}
+	}
+	r.kinds[kind] = typ
+	r.types[typ] = kind
+	return nil
+}
+
+// Kinds lists the registered kinds sorted.
+func (r *KindRegistry) Kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.kinds))
+	for kind := range r.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// PayloadFor wraps data into a payload of the kind registered for T.
+func PayloadFor[T any](r *KindRegistry, data T) (Payload[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	r.mu.RLock()
+	kind, found := r.types[typ]
+	r.mu.RUnlock()
+	if !found {
+		return Payload[T]{}, &UnknownKindError{Kind: typ.String()}
+	}
+	return Payload[T]{Kind: kind, Data: data}, nil
+}
+
+// DecodedEnvelope is an envelope with its data decoded into the type
+// registered for its kind, handlers switch on the type of Data.
+type DecodedEnvelope struct {
+	Envelope *EnvelopeT
+	Data     interface{}
+}
+
+// Decode decodes the JSON of an envelope and its data by data.kind. The
+// id is not checked, use Parse for that.
+func (r *KindRegistry) Decode(b []byte, opts ...ParseOption) (*DecodedEnvelope, error) {
+	env, err := UnmarshalEnvelopeT(b)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		data, err := jsonData(b)
+		if err != nil {
+			return nil, err
+		}
+		if err := newParseOptions(opts).check(env, data); err != nil {
+			return nil, err
+		}
+	}
+	return r.decode(env, b)
+}
+
+// Parse is Decode which verifies the id of the envelope.
+func (r *KindRegistry) Parse(b []byte, opts ...ParseOption) (*DecodedEnvelope, error) {
+	env, err := UnmarshalEnvelopeT(b)
+	if err != nil {
+		return nil, err
+	}
+	data, err := jsonData(b)
+	if err != nil {
+		return nil, err
+	}
+	o := newParseOptions(opts)
+	if err := verifyID(o.idGenerator, env, data); err != nil {
+		return nil, err
+	}
+	if err := o.check(env, data); err != nil {
+		return nil, err
+	}
+	return r.decode(env, b)
+}
+
+func (r *KindRegistry) decode(env *EnvelopeT, b []byte) (*DecodedEnvelope, error) {
+	kind := env.Data.Kind
+	r.mu.RLock()
+	typ, found := r.kinds[kind]
+	r.mu.RUnlock()
+	if !found {
+		return nil, &UnknownKindError{Kind: kind}
+	}
+	// the data decodes from its raw JSON into the registered type, the
+	// data of EnvelopeT holds json.Number from DecodeDict
+	raw := struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	data := reflect.New(typ)
+	if err := json.Unmarshal(raw.Data.Data, data.Interface()); err != nil {
+		return nil, &KindDecodeError{Kind: kind, Err: err}
+	}
+	return &DecodedEnvelope{
+		Envelope: env,
+		Data:     data.Elem().Interface(),
+	}, nil
+}
@@ -0,0 +1,118 @@
+package c5
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RegistrySuite struct {
+	suite.Suite
+	registry *KindRegistry
+}
+
+func (s *RegistrySuite) SetupTest() {
+	s.registry = NewKindRegistry()
+	assert.NoError(s.T(), RegisterKind[SampleY](s.registry, "sample-y"))
+	assert.NoError(s.T(), RegisterKindVersion[SampleNameDate](s.registry, "sample-name-date", "2"))
+}
+
+func (s *RegistrySuite) envelope(data interface{}) []byte {
+	return []byte(*NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{},
+		Data:          data,
+		TimeGenerator: mtimer,
+	}).AsJson())
+}
+
+func (s *RegistrySuite) TestDecode() {
+	y := SampleY{Y: 4}
+	b := s.envelope(PayloadT1{Kind: "sample-y", Data: y.ToDict()})
+	decoded, err := s.registry.Decode(b)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), y, decoded.Data)
+	assert.Equal(s.T(), "sample-y", decoded.Envelope.Data.Kind)
+
+	nd := SampleNameDate{Name: "object", Date: "2021-05-20"}
+	payload, err := PayloadFor(s.registry, nd)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "sample-name-date@2", payload.Kind)
+	decoded, err = s.registry.Parse(s.envelope(payload))
+	assert.NoError(s.T(), err)
+	switch v := decoded.Data.(type) {
+	case SampleNameDate:
+		assert.Equal(s.T(), nd, v)
+	default:
+		s.T().Errorf("unexpected type %T", v)
+	}
+}
+
+func (s *RegistrySuite) TestUnknownKind() {
+	b := s.envelope(PayloadT1{Kind: "sample-name-date", Data: map[string]interface{}{}})
+	_, err := s.registry.Decode(b)
+	var kerr *UnknownKindError
+	assert.True(s.T(), errors.As(err, &kerr))
+	assert.Equal(s.T(), "sample-name-date", kerr.Kind)
+
+	_, err = PayloadFor(s.registry, 4711)
+	assert.True(s.T(), errors.As(err, &kerr))
+	assert.Equal(s.T(), "int", kerr.Kind)
+}
+
+func (s *RegistrySuite) TestDecodeError() {
+	b := s.envelope(PayloadT1{Kind: "sample-y", Data: map[string]interface{}{"y": "four"}})
+	_, err := s.registry.Decode(b)
+	var derr *KindDecodeError
+	assert.True(s.T(), errors.As(err, &derr))
+	assert.Equal(s.T(), "sample-y", derr.Kind)
+}
+
+func (s *RegistrySuite) TestParseChecksID() {
+	b := s.envelope(PayloadT1{Kind: "sample-y", Data: map[string]interface{}{"y": 4}})
+	_, err := s.registry.Parse(b)
+	assert.NoError(s.T(), err)
+
+	env, err := UnmarshalEnvelopeT(b)
+	assert.NoError(s.T(), err)
+	env.Data.Data["y"] = 5
+	b, err = env.Marshal()
+	assert.NoError(s.T(), err)
+	_, err = s.registry.Decode(b)
+	assert.NoError(s.T(), err)
+	_, err = s.registry.Parse(b)
+	var merr *IDMismatchError
+	assert.True(s.T(), errors.As(err, &merr))
+}
+
+func (s *RegistrySuite) TestDuplicate() {
+	var derr *DuplicateKindError
+	assert.True(s.T(), errors.As(RegisterKind[SampleNameDate](s.registry, "sample-y"), &derr))
+	assert.Equal(s.T(), "sample-y", derr.Kind)
+	assert.True(s.T(), errors.As(RegisterKind[SampleY](s.registry, "other"), &derr))
+	assert.Equal(s.T(), "sample-y", derr.Kind)
+	assert.Equal(s.T(), []string{"sample-name-date@2", "sample-y"}, s.registry.Kinds())
+}
+
+func (s *RegistrySuite) TestConcurrent() {
+	b := s.envelope(PayloadT1{Kind: "sample-y", Data: map[string]interface{}{"y": 4}})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.registry.Decode(b)
+			assert.NoError(s.T(), err)
+			RegisterKind[[]int](s.registry, "list")
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(s.T(), s.registry.Kinds(), 3)
+}
+
+func TestRegistrySuite(t *testing.T) {
+	suite.Run(t, new(RegistrySuite))
+}
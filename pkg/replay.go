@@ -0,0 +1,134 @@
+package c5
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ReplayStore remembers the ids a ReplayGuard has seen. Implementations
+// must be safe for concurrent use, e.g. a shared cache for a cluster of
+// gateways.
+type ReplayStore interface {
+	// Add remembers id until the time until and reports if it was
+	// remembered already and not expired at now. Checking and adding is one
+	// step, so two concurrent Adds of an id see it once.
+	Add(id string, until time.Time, now time.Time) (bool, error)
+}
+
+type replayEntry struct {
+	id    string
+	until time.Time
+}
+
+// MemoryReplayStore is a ReplayStore in memory, bounded in size by
+// dropping the oldest ids and in time by their expiry. The ids are kept in
+// the order they were added, with the fixed window of a ReplayGuard this
+// is the order of their expiry.
+type MemoryReplayStore struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *replayEntry, the most recently added first
+	entries map[string]*list.Element
+}
+
+// NewMemoryReplayStore keeps up to size ids, no limit if size is 0.
+func NewMemoryReplayStore(size int) *MemoryReplayStore {
+	return &MemoryReplayStore{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (m *MemoryReplayStore) Add(id string, until time.Time, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// the expired ids drop off the end first
+	for back := m.order.Back(); back != nil && !back.Value.(*replayEntry).until.After(now); back = m.order.Back() {
+		m.remove(back)
+	}
+	if elem, found := m.entries[id]; found {
+		// a hit does not move the id, the order stays the one of the
+		// expiry the sweep above relies on
+		if elem.Value.(*replayEntry).until.After(now) {
+			return true, nil
+		}
+		m.remove(elem)
+	}
+	m.entries[id] = m.order.PushFront(&replayEntry{id: id, until: until})
+	if m.size > 0 && m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+	return false, nil
+}
+
+func (m *MemoryReplayStore) remove(elem *list.Element) {
+	m.order.Remove(elem)
+	delete(m.entries, elem.Value.(*replayEntry).id)
+}
+
+// Len is the number of ids remembered, expired ones included until the
+// next Add drops them.
+func (m *MemoryReplayStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// ReplayGuard rejects envelopes whose id it has seen within Window. The
+// ids are "<t>-<hash>", so a replay is a duplicate of the same message.
+// Pair it with a Freshness whose MaxAge plus twice the ClockSkew is at most
+// Window: older replays are rejected as stale then, so the guard never has
+// to remember more. It is safe for concurrent use.
+type ReplayGuard struct {
+	// Window is how long an id is remembered after it was first seen
+	Window time.Duration
+	// Store remembers the ids
+	Store ReplayStore
+	// TimeGenerator is the clock of the guard, defaults to the real one
+	TimeGenerator TimeGenerator
+}
+
+// NewReplayGuard remembers up to size ids for window in memory.
+func NewReplayGuard(window time.Duration, size int) *ReplayGuard {
+	return &ReplayGuard{
+		Window: window,
+		Store:  NewMemoryReplayStore(size),
+	}
+}
+
+func (g *ReplayGuard) now() time.Time {
+	if g.TimeGenerator == nil {
+		return time.Now()
+	}
+	return g.TimeGenerator.Now()
+}
+
+// Seen remembers id and reports if it was seen within the window.
+func (g *ReplayGuard) Seen(id string) (bool, error) {
+	now := g.now()
+	return g.Store.Add(id, now.Add(g.Window), now)
+}
+
+// Check remembers the id of env and returns a ReplayedEnvelopeError if it
+// was seen within the window.
+func (g *ReplayGuard) Check(env *EnvelopeT) error {
+	seen, err := g.Seen(env.ID)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return &ReplayedEnvelopeError{ID: env.ID}
+	}
+	return nil
+}
+
+// CheckEnvelope is Check for the envelope s renders.
+func (g *ReplayGuard) CheckEnvelope(s *SimpleEnvelope) error {
+	env, err := s.AsEnvelopeE()
+	if err != nil {
+		return err
+	}
+	return g.Check(env)
+}
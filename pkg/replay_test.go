@@ -0,0 +1,137 @@
+package c5
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReplaySuite struct {
+	suite.Suite
+}
+
+func (s *ReplaySuite) TestSeen() {
+	c := at(0)
+	g := NewReplayGuard(time.Minute, 10)
+	g.TimeGenerator = c
+	seen, err := g.Seen("a")
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), seen)
+	seen, _ = g.Seen("a")
+	assert.True(s.T(), seen)
+	seen, _ = g.Seen("b")
+	assert.False(s.T(), seen)
+
+	c.now = c.now.Add(time.Minute)
+	seen, _ = g.Seen("a")
+	assert.False(s.T(), seen, "the window passed")
+	seen, _ = g.Seen("a")
+	assert.True(s.T(), seen)
+}
+
+func (s *ReplaySuite) TestStoreBounds() {
+	now := mtimer.Now()
+	store := NewMemoryReplayStore(2)
+	add := func(id string, at time.Time) bool {
+		seen, err := store.Add(id, at.Add(time.Minute), at)
+		assert.NoError(s.T(), err)
+		return seen
+	}
+	assert.False(s.T(), add("a", now))
+	assert.False(s.T(), add("b", now))
+	assert.True(s.T(), add("a", now))
+	// c pushes out a, the oldest, seeing it again does not renew it
+	assert.False(s.T(), add("c", now))
+	assert.Equal(s.T(), 2, store.Len())
+	assert.True(s.T(), add("b", now))
+	assert.False(s.T(), add("a", now))
+
+	// all expired, they drop on the next add
+	assert.False(s.T(), add("d", now.Add(time.Hour)))
+	assert.Equal(s.T(), 1, store.Len())
+}
+
+func (s *ReplaySuite) TestHitKeepsExpiryOrder() {
+	start := mtimer.Now()
+	store := NewMemoryReplayStore(2)
+	add := func(id string, offset time.Duration) bool {
+		seen, err := store.Add(id, start.Add(offset+10*time.Second), start.Add(offset))
+		assert.NoError(s.T(), err)
+		return seen
+	}
+	assert.False(s.T(), add("a", 0))
+	assert.False(s.T(), add("b", 5*time.Second))
+	assert.True(s.T(), add("a", 6*time.Second))
+	// a expired at 10s and drops, b is live until 15s
+	assert.False(s.T(), add("c", 11*time.Second))
+	assert.True(s.T(), add("b", 12*time.Second))
+	assert.False(s.T(), add("a", 12*time.Second))
+}
+
+func (s *ReplaySuite) TestUnbounded() {
+	store := NewMemoryReplayStore(0)
+	now := mtimer.Now()
+	for i := 0; i < 100; i++ {
+		_, err := store.Add(fmt.Sprint(i), now.Add(time.Minute), now)
+		assert.NoError(s.T(), err)
+	}
+	assert.Equal(s.T(), 100, store.Len())
+}
+
+func (s *ReplaySuite) TestParse() {
+	envJson := []byte(*NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{},
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+		TimeGenerator: mtimer,
+	}).AsJson())
+	g := NewReplayGuard(time.Minute, 10)
+	g.TimeGenerator = mtimer
+	stale := WithFreshness(&Freshness{TimeGenerator: at(time.Hour), MaxAge: time.Minute})
+
+	_, err := ParseSimpleEnvelope(envJson, stale, WithReplayGuard(g))
+	var expired *EnvelopeExpiredError
+	assert.True(s.T(), errors.As(err, &expired))
+	// a rejected envelope is not remembered
+	_, err = ParseSimpleEnvelope(envJson, WithReplayGuard(g))
+	assert.NoError(s.T(), err)
+	var replayed *ReplayedEnvelopeError
+	_, err = ParseSimpleEnvelope(envJson, WithReplayGuard(g))
+	if assert.True(s.T(), errors.As(err, &replayed)) {
+		env, err := UnmarshalEnvelopeT(envJson)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), env.ID, replayed.ID)
+	}
+	_, err = ParseEnvelope[map[string]interface{}](envJson, WithReplayGuard(g))
+	assert.True(s.T(), errors.As(err, &replayed))
+}
+
+func (s *ReplaySuite) TestConcurrent() {
+	g := NewReplayGuard(time.Minute, 1000)
+	var fresh int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := 0; id < 100; id++ {
+				err := g.Check(&EnvelopeT{ID: fmt.Sprint(id)})
+				if err == nil {
+					atomic.AddInt32(&fresh, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(s.T(), int32(100), fresh)
+}
+
+func TestReplaySuite(t *testing.T) {
+	suite.Run(t, new(ReplaySuite))
+}
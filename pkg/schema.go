@@ -0,0 +1,195 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is a compiled JSON Schema. It covers the part of draft 2020-12
+// payloads need: type, enum, const, required, properties,
+// additionalProperties, items, pattern, minLength, maxLength, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, minItems, maxItems and the
+// boolean schemas. Other keywords are ignored like annotations.
+type Schema struct {
+	never        bool // the false schema
+	types        []string
+	enum         []interface{}
+	properties   map[string]*Schema
+	required     []string
+	additional   *Schema
+	noAdditional bool
+	items        *Schema
+	pattern      *regexp.Regexp
+	minLength    *int
+	maxLength    *int
+	minimum      *float64
+	maximum      *float64
+	exclMinimum  *float64
+	exclMaximum  *float64
+	minItems     *int
+	maxItems     *int
+}
+
+var schemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// CompileSchema compiles the JSON Schema document doc.
+func CompileSchema(doc []byte) (*Schema, error) {
+	var raw interface{}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return compileSchema(raw, "")
+}
+
+func compileSchema(raw interface{}, pointer string) (*Schema, error) {
+	switch v := raw.(type) {
+	case bool:
+		return &Schema{never: !v}, nil
+	case map[string]interface{}:
+		return compileSchemaObject(v, pointer)
+	}
+	return nil, &InvalidSchemaError{Pointer: pointer, Message: "schema must be an object or a boolean"}
+}
+
+func compileSchemaObject(raw map[string]interface{}, pointer string) (*Schema, error) {
+	s := &Schema{}
+	var err error
+	if t, found := raw["type"]; found {
+		switch v := t.(type) {
+		case string:
+			s.types = []string{v}
+		case []interface{}:
+			for _, vt := range v {
+				str, ok := vt.(string)
+				if !ok {
+					return nil, &InvalidSchemaError{Pointer: pointer + "/type", Message: "type must be a string"}
+				}
+				s.types = append(s.types, str)
+			}
+		default:
+			return nil, &InvalidSchemaError{Pointer: pointer + "/type", Message: "type must be a string or an array"}
+		}
+		for _, typ := range s.types {
+			if !schemaTypes[typ] {
+				return nil, &InvalidSchemaError{Pointer: pointer + "/type", Message: fmt.Sprintf("unknown type '%v'", typ)}
+			}
+		}
+	}
+	if e, found := raw["enum"]; found {
+		enum, ok := e.([]interface{})
+		if !ok {
+			return nil, &InvalidSchemaError{Pointer: pointer + "/enum", Message: "enum must be an array"}
+		}
+		s.enum = enum
+	}
+	if c, found := raw["const"]; found {
+		s.enum = []interface{}{c}
+	}
+	if r, found := raw["required"]; found {
+		required, ok := r.([]interface{})
+		if !ok {
+			return nil, &InvalidSchemaError{Pointer: pointer + "/required", Message: "required must be an array"}
+		}
+		for _, name := range required {
+			str, ok := name.(string)
+			if !ok {
+				return nil, &InvalidSchemaError{Pointer: pointer + "/required", Message: "required must list strings"}
+			}
+			s.required = append(s.required, str)
+		}
+	}
+	if p, found := raw["properties"]; found {
+		props, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, &InvalidSchemaError{Pointer: pointer + "/properties", Message: "properties must be an object"}
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for name, sub := range props {
+			if s.properties[name], err = compileSchema(sub, pointer+"/properties/"+escapePointer(name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if a, found := raw["additionalProperties"]; found {
+		if allowed, ok := a.(bool); ok {
+			s.noAdditional = !allowed
+		} else if s.additional, err = compileSchema(a, pointer+"/additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	if i, found := raw["items"]; found {
+		if s.items, err = compileSchema(i, pointer+"/items"); err != nil {
+			return nil, err
+		}
+	}
+	if p, found := raw["pattern"]; found {
+		str, ok := p.(string)
+		if !ok {
+			return nil, &InvalidSchemaError{Pointer: pointer + "/pattern", Message: "pattern must be a string"}
+		}
+		if s.pattern, err = regexp.Compile(str); err != nil {
+			return nil, &InvalidSchemaError{Pointer: pointer + "/pattern", Message: err.Error()}
+		}
+	}
+	for keyword, target := range map[string]**int{
+		"minLength": &s.minLength, "maxLength": &s.maxLength,
+		"minItems": &s.minItems, "maxItems": &s.maxItems,
+	} {
+		if *target, err = schemaCount(raw, keyword, pointer); err != nil {
+			return nil, err
+		}
+	}
+	for keyword, target := range map[string]**float64{
+		"minimum": &s.minimum, "maximum": &s.maximum,
+		"exclusiveMinimum": &s.exclMinimum, "exclusiveMaximum": &s.exclMaximum,
+	} {
+		if *target, err = schemaNumber(raw, keyword, pointer); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(s.required)
+	return s, nil
+}
+
+func schemaNumber(raw map[string]interface{}, keyword string, pointer string) (*float64, error) {
+	v, found := raw[keyword]
+	if !found {
+		return nil, nil
+	}
+	num, ok := v.(json.Number)
+	if ok {
+		if f, err := num.Float64(); err == nil {
+			return &f, nil
+		}
+	}
+	return nil, &InvalidSchemaError{Pointer: pointer + "/" + keyword, Message: keyword + " must be a number"}
+}
+
+func schemaCount(raw map[string]interface{}, keyword string, pointer string) (*int, error) {
+	v, found := raw[keyword]
+	if !found {
+		return nil, nil
+	}
+	num, ok := v.(json.Number)
+	if ok {
+		if i, err := strconv.Atoi(num.String()); err == nil && i >= 0 {
+			return &i, nil
+		}
+	}
+	return nil, &InvalidSchemaError{Pointer: pointer + "/" + keyword, Message: keyword + " must be a non-negative integer"}
+}
+
+// escapePointer escapes name as JSON pointer token
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
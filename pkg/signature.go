@@ -0,0 +1,191 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const ED25519 = "Ed25519"
+
+type Signature struct {
+	Alg   string `json:"alg"`
+	KeyID string `json:"kid"`
+	Sig   string `json:"sig"`
+}
+
+// SignedEnvelope carries an envelope together with a signature over the
+// canonical JSON of the whole envelope.
+type SignedEnvelope struct {
+	Envelope  EnvelopeT `json:"envelope"`
+	Signature Signature `json:"signature"`
+}
+
+type Ed25519Signer struct {
+	KeyID      string
+	PrivateKey ed25519.PrivateKey
+}
+
+// KeyResolver returns the public key of the signer named by keyID.
+type KeyResolver interface {
+	ResolveKey(keyID string) (ed25519.PublicKey, error)
+}
+
+type StaticKeyResolver map[string]ed25519.PublicKey
+
+func (r StaticKeyResolver) ResolveKey(keyID string) (ed25519.PublicKey, error) {
+	key, found := r[keyID]
+	if !found {
+		return nil, &UnknownKeyError{KeyID: keyID}
+	}
+	return key, nil
+}
+
+// CanonicalJson renders env as compact JSON with sorted keys. These are the
+// bytes which get signed.
+func CanonicalJson(env *EnvelopeT) ([]byte, error) {
+	var out strings.Builder
+	jsonC := NewJsonCollector(func(str string) {
+		out.WriteString(str)
+	}, nil)
+	if err := SortKeysE(*env, jsonC.AppendE); err != nil {
+		return nil, err
+	}
+	return []byte(out.String()), nil
+}
+
+func (signer *Ed25519Signer) sign(msg []byte) *Signature {
+	return &Signature{
+		Alg:   ED25519,
+		KeyID: signer.KeyID,
+		Sig:   base58.Encode(ed25519.Sign(signer.PrivateKey, msg)),
+	}
+}
+
+func SignEnvelopeT(env *EnvelopeT, signer *Ed25519Signer) (*SignedEnvelope, error) {
+	msg, err := CanonicalJson(env)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedEnvelope{
+		Envelope:  *env,
+		Signature: *signer.sign(msg),
+	}, nil
+}
+
+// SignatureCollector renders the tokens of an envelope to its canonical
+// JSON and signs it once the walk is done. Ed25519 needs the whole message,
+// so the JSON is kept in memory.
+type SignatureCollector struct {
+	signer *Ed25519Signer
+	msg    bytes.Buffer
+	jsonC  *JsonCollector
+}
+
+func NewSignatureCollector(signer *Ed25519Signer) *SignatureCollector {
+	c := &SignatureCollector{signer: signer}
+	c.jsonC = NewJsonWriterCollector(&c.msg, nil)
+	return c
+}
+
+func (c *SignatureCollector) AppendE(sval SVal) error {
+	return c.jsonC.AppendE(sval)
+}
+
+func (c *SignatureCollector) Signature() (*Signature, error) {
+	if err := c.jsonC.Flush(); err != nil {
+		return nil, err
+	}
+	return c.signer.sign(c.msg.Bytes()), nil
+}
+
+// Sign computes id, JSON and signature of the envelope in a single walk.
+func (s *SimpleEnvelope) Sign(signer *Ed25519Signer) (*SignedEnvelope, error) {
+	sigC := NewSignatureCollector(signer)
+	if err := s.Collect(sigC); err != nil {
+		return nil, err
+	}
+	sig, err := sigC.Signature()
+	if err != nil {
+		return nil, err
+	}
+	return &SignedEnvelope{
+		Envelope:  *s.Envelope,
+		Signature: *sig,
+	}, nil
+}
+
+// Verify checks the signature with the key keys resolves for its key id.
+// The content id of the envelope is not checked, use VerifyEnvelopeT for
+// that.
+func (r *SignedEnvelope) Verify(keys KeyResolver) error {
+	if r.Signature.Alg != ED25519 {
+		return &UnsupportedAlgorithmError{Alg: r.Signature.Alg}
+	}
+	key, err := keys.ResolveKey(r.Signature.KeyID)
+	if err != nil {
+		return err
+	}
+	msg, err := CanonicalJson(&r.Envelope)
+	if err != nil {
+		return err
+	}
+	sig := base58.Decode(r.Signature.Sig)
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, msg, sig) {
+		return &InvalidSignatureError{KeyID: r.Signature.KeyID}
+	}
+	return nil
+}
+
+func (r *SignedEnvelope) AsJson() (*string, error) {
+	var out strings.Builder
+	jsonC := NewJsonCollector(func(str string) {
+		out.WriteString(str)
+	}, nil)
+	if err := SortKeysE(*r, jsonC.AppendE); err != nil {
+		return nil, err
+	}
+	str := out.String()
+	return &str, nil
+}
+
+// UnmarshalSignedEnvelope decodes a signed envelope, its numbers stay
+// literal so the canonical JSON reads like the one which got signed.
+func UnmarshalSignedEnvelope(data []byte) (*SignedEnvelope, error) {
+	dict, err := DecodeDict(data)
+	if err != nil {
+		return nil, err
+	}
+	if dict["envelope"] == nil || dict["signature"] == nil {
+		return nil, fmt.Errorf("signed envelope needs envelope and signature")
+	}
+	envelope, err := DictObject(dict["envelope"], "/envelope")
+	if err != nil {
+		return nil, err
+	}
+	signature, err := DictObject(dict["signature"], "/signature")
+	if err != nil {
+		return nil, err
+	}
+	r := SignedEnvelope{}
+	for key, field := range map[string]*string{
+		"alg": &r.Signature.Alg,
+		"kid": &r.Signature.KeyID,
+		"sig": &r.Signature.Sig,
+	} {
+		v, found := signature[key]
+		if !found {
+			continue
+		}
+		if *field, err = DictString(v, "/signature/"+key); err != nil {
+			return nil, err
+		}
+	}
+	if err := FromDictEnvelopeT(envelope, &r.Envelope); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,116 @@
+package c5
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SignatureSuite struct {
+	suite.Suite
+	signer *Ed25519Signer
+	keys   StaticKeyResolver
+}
+
+func (s *SignatureSuite) SetupTest() {
+	key := ed25519.NewKeyFromSeed([]byte("c5-envelope-test-seed-0123456789"))
+	s.signer = &Ed25519Signer{KeyID: "test-key", PrivateKey: key}
+	s.keys = StaticKeyResolver{"test-key": key.Public().(ed25519.PublicKey)}
+}
+
+func (s *SignatureSuite) newSimpleEnvelope() *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{"name": "object", "date": "2021-05-20"},
+		},
+		Dst:           []string{"a", "b"},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *SignatureSuite) TestCanonicalJson() {
+	se := s.newSimpleEnvelope()
+	js := *se.AsJson()
+	cj, err := CanonicalJson(se.AsEnvelope())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), js, string(cj))
+}
+
+func (s *SignatureSuite) TestSignVerifyRoundTrip() {
+	signed, err := s.newSimpleEnvelope().Sign(s.signer)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), ED25519, signed.Signature.Alg)
+	assert.Equal(s.T(), "test-key", signed.Signature.KeyID)
+	assert.NoError(s.T(), signed.Verify(s.keys))
+
+	js, err := signed.AsJson()
+	assert.NoError(s.T(), err)
+	parsed, err := UnmarshalSignedEnvelope([]byte(*js))
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), parsed.Verify(s.keys))
+	assert.NoError(s.T(), VerifyEnvelopeT(&parsed.Envelope))
+}
+
+func (s *SignatureSuite) TestLargeIntegerRoundTrip() {
+	signed, err := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"y": int64(9007199254740993)}},
+		Dst:           []string{},
+		TimeGenerator: mtimer,
+	}).Sign(s.signer)
+	assert.NoError(s.T(), err)
+	js, err := signed.AsJson()
+	assert.NoError(s.T(), err)
+	assert.Contains(s.T(), *js, `"y":9007199254740993`)
+	parsed, err := UnmarshalSignedEnvelope([]byte(*js))
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), parsed.Verify(s.keys))
+	assert.NoError(s.T(), VerifyEnvelopeT(&parsed.Envelope))
+}
+
+func (s *SignatureSuite) TestTamperedHeader() {
+	signed, err := s.newSimpleEnvelope().Sign(s.signer)
+	assert.NoError(s.T(), err)
+	js, _ := signed.AsJson()
+	parsed, err := UnmarshalSignedEnvelope([]byte(strings.Replace(*js, `"src":"test case"`, `"src":"evil"`, 1)))
+	assert.NoError(s.T(), err)
+	var serr *InvalidSignatureError
+	assert.True(s.T(), errors.As(parsed.Verify(s.keys), &serr))
+	assert.Equal(s.T(), "test-key", serr.KeyID)
+}
+
+func (s *SignatureSuite) TestUnknownKey() {
+	signed, err := s.newSimpleEnvelope().Sign(s.signer)
+	assert.NoError(s.T(), err)
+	var kerr *UnknownKeyError
+	assert.True(s.T(), errors.As(signed.Verify(StaticKeyResolver{}), &kerr))
+	assert.Equal(s.T(), "test-key", kerr.KeyID)
+}
+
+func (s *SignatureSuite) TestWrongKey() {
+	signed, err := s.newSimpleEnvelope().Sign(s.signer)
+	assert.NoError(s.T(), err)
+	other := ed25519.NewKeyFromSeed([]byte("c5-envelope-other-seed-012345678"))
+	var serr *InvalidSignatureError
+	assert.True(s.T(), errors.As(signed.Verify(StaticKeyResolver{
+		"test-key": other.Public().(ed25519.PublicKey),
+	}), &serr))
+}
+
+func (s *SignatureSuite) TestUnsupportedAlgorithm() {
+	signed, err := s.newSimpleEnvelope().Sign(s.signer)
+	assert.NoError(s.T(), err)
+	signed.Signature.Alg = "none"
+	var aerr *UnsupportedAlgorithmError
+	assert.True(s.T(), errors.As(signed.Verify(s.keys), &aerr))
+}
+
+func TestSignatureSuite(t *testing.T) {
+	suite.Run(t, new(SignatureSuite))
+}
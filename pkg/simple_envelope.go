@@ -1,22 +1,29 @@
 package c5
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	hashLib "hash"
+	"io"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/btcsuite/btcutil/base58"
 )
 
 const JSISOStringFormat = "2006-01-02T15:04:05.999Z07:00"
 
 type ValType interface {
 	ToString() *string
+	ToStringE() (*string, error)
 	AsValue() interface{}
 }
 
@@ -27,12 +34,20 @@ type JsonValType struct {
 // type Dict T // map[string]interface{}
 
 func (j JsonValType) ToString() *string {
-	out, err := json.Marshal(j.Val)
+	str, err := j.ToStringE()
 	if err != nil {
 		panic(err)
 	}
+	return str
+}
+
+func (j JsonValType) ToStringE() (*string, error) {
+	out, err := json.Marshal(j.Val)
+	if err != nil {
+		return nil, err
+	}
 	str := string(out)
-	return &str
+	return &str, nil
 }
 
 func (j JsonValType) AsValue() interface{} {
@@ -47,6 +62,10 @@ func (p PlainValType) ToString() *string {
 	return p.val
 }
 
+func (p PlainValType) ToStringE() (*string, error) {
+	return p.val, nil
+}
+
 func (p PlainValType) AsValue() interface{} {
 	return p.val
 }
@@ -86,74 +105,258 @@ type SVal struct {
 
 type SvalFn func(prob SVal)
 
+type SvalFnE func(prob SVal) error
+
 func SortKeys(e interface{}, out SvalFn, paths ...string) {
+	err := SortKeysE(e, func(prob SVal) error {
+		out(prob)
+		return nil
+	}, paths...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func SortKeysE(e interface{}, out SvalFnE, paths ...string) error {
+	return SortKeysOrderedE(e, out, nil, paths...)
+}
+
+// KeyLess orders the keys of an object, nil sorts by bytes.
+type KeyLess func(a, b string) bool
+
+func sortStrings(keys []string, less KeyLess) {
+	if less == nil {
+		sort.Strings(keys)
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+}
+
+func SortKeysOrderedE(e interface{}, out SvalFnE, less KeyLess, paths ...string) error {
 	path := ""
 	if len(paths) > 0 {
 		path = paths[0]
 	}
-	_, isTime := e.(time.Time)
-	k := reflect.Invalid
-	if e != nil {
-		k = reflect.TypeOf(e).Kind()
+	w := &sortKeysWalker{
+		out:  out,
+		less: less,
+		ptrs: map[uintptr]struct{}{},
 	}
-	valOf := reflect.ValueOf(e)
-	if k == reflect.Slice {
-		out(SVal{path: path, outState: ARRAY_START})
-		for i := 0; i < valOf.Len(); i++ {
-			SortKeys(valOf.Index(i).Interface(), out, fmt.Sprintf("%s/%d", path, i))
+	// the root is not copied, a value is walked as unaddressable like
+	// encoding/json does, so pointer receivers apply behind pointers only
+	return w.walk(reflect.ValueOf(e), path)
+}
+
+type sortKeysWalker struct {
+	out  SvalFnE
+	less KeyLess
+	// pointers on the current path, to bail out of cycles
+	ptrs map[uintptr]struct{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (w *sortKeysWalker) walk(valOf reflect.Value, path string) error {
+	for valOf.Kind() == reflect.Ptr || valOf.Kind() == reflect.Interface {
+		if valOf.IsNil() {
+			return w.out(SVal{val: JsonValType{nil}, outState: NONE, path: path})
 		}
-		out(SVal{outState: ARRAY_END, path: path})
-		return
-	} else if k == reflect.Struct && !isTime {
-		out(SVal{outState: OBJECT_START, path: path})
-		keys := make([]string, 0, valOf.NumField())
-		m := make(map[string]interface{})
-		for i := 0; i < valOf.NumField(); i++ {
-			fl := valOf.Type().Field(i)
-			if !fl.IsExported() {
-				panic(fmt.Sprintf("Field '%v' is not exported!", fl.Name))
-			}
-			fieldName := fl.Name
-			t, hasTag := fl.Tag.Lookup("json")
-			if hasTag {
-				fieldName = t
+		if valOf.Kind() == reflect.Ptr {
+			ptr := valOf.Pointer()
+			if _, seen := w.ptrs[ptr]; seen {
+				return &UnmarshalableValueError{
+					Path: path,
+					Err:  fmt.Errorf("encountered a cycle via %v", valOf.Type()),
+				}
 			}
-			m[fieldName] = valOf.Field(i).Interface()
-			keys = append(keys, fieldName)
+			w.ptrs[ptr] = struct{}{}
+			defer delete(w.ptrs, ptr)
 		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			sub := fmt.Sprintf("%s/%s", path, key)
-			out(SVal{attribute: key, outState: NONE, path: sub})
-			SortKeys(m[key], out, sub)
+		valOf = valOf.Elem()
+	}
+	if !valOf.IsValid() {
+		return w.out(SVal{val: JsonValType{nil}, outState: NONE, path: path})
+	}
+	// time.Time keeps its JS ISO format for the hash
+	if valOf.Type() != timeType {
+		if done, err := w.marshaled(valOf, path); done {
+			return err
 		}
-		out(SVal{outState: OBJECT_END, path: path})
-		return
 	}
-	if k == reflect.Map && !isTime {
-		out(SVal{outState: OBJECT_START, path: path})
-		mappe := e.(map[string]interface{})
-		keys := make([]string, len(mappe))
-		idx := 0
-		for key := range mappe {
-			keys[idx] = key
-			idx++
+	if isBytes(valOf) {
+		if valOf.IsNil() {
+			return w.out(SVal{val: JsonValType{nil}, outState: NONE, path: path})
 		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			sub := fmt.Sprintf("%s/%s", path, key)
-			out(SVal{attribute: key, outState: NONE, path: sub})
-			SortKeys(mappe[key], out, sub)
+		str := base64.StdEncoding.EncodeToString(valOf.Bytes())
+		return w.out(SVal{val: JsonValType{str}, outState: NONE, path: path})
+	}
+	switch valOf.Kind() {
+	case reflect.Slice, reflect.Array:
+		if err := w.out(SVal{path: path, outState: ARRAY_START}); err != nil {
+			return err
 		}
-		out(SVal{outState: OBJECT_END, path: path})
-		return
+		for i := 0; i < valOf.Len(); i++ {
+			if err := w.walk(valOf.Index(i), fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+		return w.out(SVal{outState: ARRAY_END, path: path})
+	case reflect.Struct:
+		if valOf.Type() == timeType {
+			break
+		}
+		if err := w.out(SVal{outState: OBJECT_START, path: path}); err != nil {
+			return err
+		}
+		fields := cachedFields(valOf.Type())
+		for _, fl := range fields {
+			field, found := fieldByIndex(valOf, fl.index)
+			if !found || (fl.omitEmpty && isEmptyValue(field)) {
+				continue
+			}
+			sub := fmt.Sprintf("%s/%s", path, fl.name)
+			if err := w.out(SVal{attribute: fl.name, outState: NONE, path: sub}); err != nil {
+				return err
+			}
+			var err error
+			if fl.quoted {
+				err = w.quoted(field, sub)
+			} else {
+				err = w.walk(field, sub)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return w.out(SVal{outState: OBJECT_END, path: path})
+	case reflect.Map:
+		if err := w.out(SVal{outState: OBJECT_START, path: path}); err != nil {
+			return err
+		}
+		keys := make([]string, 0, valOf.Len())
+		m := make(map[string]reflect.Value)
+		iter := valOf.MapRange()
+		for iter.Next() {
+			key, err := mapKey(iter.Key())
+			if err != nil {
+				return &UnmarshalableValueError{Path: path, Err: err}
+			}
+			m[key] = iter.Value()
+			keys = append(keys, key)
+		}
+		return w.object(keys, m, path)
 	}
 	// else {
 	//	fmt.Println("Reflect:", k)
 	//}
 
-	out(SVal{val: JsonValType{e}, outState: NONE, path: path})
-	return
+	return w.out(SVal{val: JsonValType{valOf.Interface()}, outState: NONE, path: path})
+}
+
+// isBytes reports a byte slice, which encoding/json writes as base64
+// string unless its elements marshal themselves.
+func isBytes(valOf reflect.Value) bool {
+	if valOf.Kind() != reflect.Slice || valOf.Type().Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	elem := reflect.PtrTo(valOf.Type().Elem())
+	return !elem.Implements(marshalerType) && !elem.Implements(textMarshalerType)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// marshaler returns valOf as json.Marshaler or encoding.TextMarshaler,
+// taking its address for pointer receivers like encoding/json does.
+func marshaler(valOf reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if valOf.Type().Implements(iface) {
+		return valOf.Interface(), true
+	}
+	if valOf.CanAddr() && reflect.PtrTo(valOf.Type()).Implements(iface) {
+		return valOf.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+// marshaled walks the JSON a json.Marshaler produces or emits the text of an
+// encoding.TextMarshaler as string. It reports false for any other value.
+func (w *sortKeysWalker) marshaled(valOf reflect.Value, path string) (bool, error) {
+	if m, ok := marshaler(valOf, marshalerType); ok {
+		b, err := m.(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return true, &UnmarshalableValueError{Path: path, Err: err}
+		}
+		var decoded interface{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&decoded); err != nil {
+			return true, &UnmarshalableValueError{Path: path, Err: err}
+		}
+		return true, w.walk(reflect.ValueOf(decoded), path)
+	}
+	if m, ok := marshaler(valOf, textMarshalerType); ok {
+		b, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return true, &UnmarshalableValueError{Path: path, Err: err}
+		}
+		return true, w.out(SVal{val: JsonValType{string(b)}, outState: NONE, path: path})
+	}
+	return false, nil
+}
+
+// quoted writes a field tagged with the "string" option as JSON string.
+func (w *sortKeysWalker) quoted(valOf reflect.Value, path string) error {
+	for valOf.Kind() == reflect.Ptr {
+		if valOf.IsNil() {
+			return w.out(SVal{val: JsonValType{nil}, outState: NONE, path: path})
+		}
+		valOf = valOf.Elem()
+	}
+	b, err := json.Marshal(valOf.Interface())
+	if err != nil {
+		return &UnmarshalableValueError{Path: path, Err: err}
+	}
+	return w.out(SVal{val: JsonValType{string(b)}, outState: NONE, path: path})
+}
+
+func (w *sortKeysWalker) object(keys []string, m map[string]reflect.Value, path string) error {
+	sortStrings(keys, w.less)
+	for _, key := range keys {
+		sub := fmt.Sprintf("%s/%s", path, key)
+		if err := w.out(SVal{attribute: key, outState: NONE, path: sub}); err != nil {
+			return err
+		}
+		if err := w.walk(m[key], sub); err != nil {
+			return err
+		}
+	}
+	return w.out(SVal{outState: OBJECT_END, path: path})
+}
+
+// mapKey turns string and integer map keys into attribute names like
+// encoding/json does.
+func mapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if tm, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if key.Kind() == reflect.Ptr && key.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type %v", key.Type())
 }
 
 type OutputFN func(str string)
@@ -174,17 +377,49 @@ func NewJsonProps(nSpaces int, newLine string) *JsonProps {
 	}
 }
 
+// jsonSink is what a JsonCollector writes to, a bufio.Writer or a
+// fragment buffer which is handed on to an OutputFN.
+type jsonSink interface {
+	io.Writer
+	io.StringWriter
+	io.ByteWriter
+}
+
 type JsonCollector struct {
 	output    OutputFN
+	sink      jsonSink
+	fragment  *bytes.Buffer // set if output is, holds the JSON of an AppendE until output gets it
+	buffered  *bufio.Writer // set if the collector writes to an io.Writer
+	err       error
 	indent    string
 	commas    []string
 	elements  []int
 	props     *JsonProps
 	nextLine  string
-	attribute string
+	attribute []byte
 }
 
 func NewJsonCollector(o OutputFN, p *JsonProps) *JsonCollector {
+	fragment := &bytes.Buffer{}
+	j := newJsonCollector(fragment, p)
+	j.output = o
+	j.fragment = fragment
+	return j
+}
+
+// NewJsonWriterCollector streams the JSON to w through a bufio.Writer,
+// call Flush once the walk is done.
+func NewJsonWriterCollector(w io.Writer, p *JsonProps) *JsonCollector {
+	buffered, ok := w.(*bufio.Writer)
+	if !ok {
+		buffered = bufio.NewWriter(w)
+	}
+	j := newJsonCollector(buffered, p)
+	j.buffered = buffered
+	return j
+}
+
+func newJsonCollector(sink jsonSink, p *JsonProps) *JsonCollector {
 	props := p
 	if props == nil {
 		props = NewJsonProps(0, "")
@@ -196,58 +431,139 @@ func NewJsonCollector(o OutputFN, p *JsonProps) *JsonCollector {
 	}
 
 	return &JsonCollector{
-		output:    o,
-		indent:    strings.Repeat(" ", props.indent),
-		commas:    []string{""},
-		elements:  []int{0},
-		props:     props,
-		nextLine:  nextLine,
-		attribute: "",
+		sink:     sink,
+		indent:   strings.Repeat(" ", props.indent),
+		commas:   []string{""},
+		elements: []int{0},
+		props:    props,
+		nextLine: nextLine,
+	}
+}
+
+// Flush writes the buffered JSON to the io.Writer of the collector.
+func (j *JsonCollector) Flush() error {
+	if j.err == nil && j.buffered != nil {
+		j.err = j.buffered.Flush()
 	}
+	return j.err
 }
 
 func (j *JsonCollector) Suffix() string {
+	var out strings.Builder
+	j.suffix(&out)
+	return out.String()
+}
+
+func (j *JsonCollector) suffix(w io.StringWriter) {
 	if j.elements[len(j.elements)-1] > 0 {
 		commas := len(j.commas)
 		if commas > 0 {
 			commas -= 1
 		}
-		return fmt.Sprintf("%v%v", j.nextLine, strings.Repeat(j.indent, commas))
+		j.check(w.WriteString(j.nextLine))
+		for i := 0; i < commas; i++ {
+			j.check(w.WriteString(j.indent))
+		}
+	}
+}
+
+func (j *JsonCollector) check(_ int, err error) {
+	if j.err == nil {
+		j.err = err
+	}
+}
+
+func (j *JsonCollector) writeByte(c byte) {
+	if err := j.sink.WriteByte(c); err != nil && j.err == nil {
+		j.err = err
 	}
-	return ""
+}
+
+// prefix writes comma, indent and attribute in front of a value
+func (j *JsonCollector) prefix() {
+	j.check(j.sink.WriteString(j.commas[len(j.commas)-1]))
+	j.suffix(j.sink)
+	j.check(j.sink.Write(j.attribute))
+	j.attribute = j.attribute[:0]
+	j.commas[len(j.commas)-1] = ","
+}
+
+func (j *JsonCollector) open(c byte) {
+	j.prefix()
+	j.writeByte(c)
+	j.commas = append(j.commas, "")
+	j.elements = append(j.elements, 0)
+}
+
+func (j *JsonCollector) close(c byte) {
+	j.commas = j.commas[:len(j.commas)-1]
+	j.suffix(j.sink)
+	j.writeByte(c)
+	j.elements = j.elements[:len(j.elements)-1]
+}
+
+// appendRaw places the value write writes to the sink of the collector
+// like a scalar.
+func (j *JsonCollector) appendRaw(write func() error) error {
+	j.elements[len(j.elements)-1]++
+	j.prefix()
+	if err := write(); err != nil {
+		return err
+	}
+	return j.emit()
+}
+
+// emit hands the fragment of an AppendE on to the OutputFN
+func (j *JsonCollector) emit() error {
+	if j.err != nil {
+		return j.err
+	}
+	if j.fragment != nil && j.fragment.Len() > 0 {
+		j.output(j.fragment.String())
+		j.fragment.Reset()
+	}
+	return nil
 }
 
 func (j *JsonCollector) Append(sVal SVal) {
-	if sVal.outState != NONE {
-		switch sVal.outState {
-		case ARRAY_START:
-			j.output(fmt.Sprintf("%v%v%v[", j.commas[len(j.commas)-1], j.Suffix(), j.attribute))
-			j.attribute = ""
-			j.commas[len(j.commas)-1] = ","
-			j.commas = append(j.commas, "")
-			j.elements = append(j.elements, 0)
-		case ARRAY_END:
-			j.commas = j.commas[:len(j.commas)-1]
-			j.output(fmt.Sprintf("%v]", j.Suffix()))
-			j.elements = j.elements[:len(j.elements)-1]
-		case OBJECT_START:
-			j.output(fmt.Sprintf("%v%v%v{", j.commas[len(j.commas)-1], j.Suffix(), j.attribute))
-			j.attribute = ""
-			j.commas[len(j.commas)-1] = ","
-			j.commas = append(j.commas, "")
-			j.elements = append(j.elements, 0)
-		case OBJECT_END:
-			j.commas = j.commas[:len(j.commas)-1]
-			j.output(fmt.Sprintf("%v}", j.Suffix()))
-			j.elements = j.elements[:len(j.elements)-1]
-		}
+	if err := j.AppendE(sVal); err != nil {
+		panic(err)
+	}
+}
+
+func (j *JsonCollector) AppendE(sVal SVal) error {
+	switch sVal.outState {
+	case ARRAY_START:
+		j.open('[')
+	case ARRAY_END:
+		j.close(']')
+	case OBJECT_START:
+		j.open('{')
+	case OBJECT_END:
+		j.close('}')
 	}
 
 	if sVal.val != nil {
-		j.elements[len(j.elements)-1]++
-		j.output(fmt.Sprintf("%v%v%v%v", j.commas[len(j.commas)-1], j.Suffix(), j.attribute, *sVal.val.ToString()))
-		j.attribute = ""
-		j.commas[len(j.commas)-1] = ","
+		var val []byte
+		var err error
+		if jval, ok := sVal.val.(JsonValType); ok {
+			val, err = json.Marshal(jval.Val)
+		} else {
+			var str *string
+			str, err = sVal.val.ToStringE()
+			if err == nil {
+				val = []byte(*str)
+			}
+		}
+		if err != nil {
+			return &UnmarshalableValueError{Path: sVal.path, Err: err}
+		}
+		if err := j.appendRaw(func() error {
+			j.check(j.sink.Write(val))
+			return nil
+		}); err != nil {
+			return err
+		}
 	}
 
 	if sVal.attribute != "" {
@@ -259,19 +575,19 @@ func (j *JsonCollector) Append(sVal SVal) {
 
 		b, err := json.Marshal(sVal.attribute)
 		if err != nil {
-			panic(err)
+			return &UnmarshalableValueError{Path: sVal.path, Err: err}
 		}
-		space := ""
+		j.attribute = append(append(j.attribute[:0], b...), ':')
 		if len(j.indent) > 0 {
-			space = " "
+			j.attribute = append(j.attribute, ' ')
 		}
-		j.attribute = fmt.Sprintf("%v:%v", string(b), space)
-
 	}
+	return j.emit()
 }
 
 type HashCollector struct {
 	hash hashLib.Hash
+	spec HashSpec
 }
 
 func NewHashCollector() *HashCollector {
@@ -280,20 +596,42 @@ func NewHashCollector() *HashCollector {
 	}
 }
 
+func NewHashCollectorSpec(spec HashSpec) (*HashCollector, error) {
+	hash, err := spec.newHash()
+	if err != nil {
+		return nil, err
+	}
+	return &HashCollector{
+		hash: hash,
+		spec: spec,
+	}, nil
+}
+
 func (h *HashCollector) Digest() string {
 	// b := []byte{}
-	return base58.Encode(h.hash.Sum(nil))
+	return h.spec.encode(h.hash.Sum(nil))
 }
 
 func (h *HashCollector) Append(sval SVal) {
+	if err := h.AppendE(sval); err != nil {
+		panic(err)
+	}
+}
+
+func (h *HashCollector) AppendE(sval SVal) error {
+	if h.spec.Tagged {
+		return h.appendTagged(sval)
+	}
 	if sval.outState != NONE {
-		return
+		return nil
 	}
 
 	// fmt.Println("SVAL", sval)
 	if sval.attribute != "" {
 		// fmt.Println("ATTRIB", sval.attribute)
-		h.hash.Write([]byte(sval.attribute))
+		if _, err := h.hash.Write([]byte(sval.attribute)); err != nil {
+			return err
+		}
 	}
 
 	if sval.val != nil {
@@ -306,8 +644,11 @@ func (h *HashCollector) Append(sval SVal) {
 			t = fmt.Sprintf("%v", vl)
 		}
 		// fmt.Println("VAL", t)
-		h.hash.Write([]byte(t))
+		if _, err := h.hash.Write([]byte(t)); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // type Payload struct {
@@ -316,24 +657,44 @@ func (h *HashCollector) Append(sval SVal) {
 // }
 
 type SimpleEnvelopeProps struct {
-	ID            string
-	Src           string
-	Dst           []string
-	T             interface{} // int64 || time.Time
-	TTL           int
-	Data          interface{} // PayloadT1
-	JsonProp      *JsonProps
-	TimeGenerator TimeGenerator
+	ID             string
+	IDGenerator    IDGenerator // makes the id if ID is not set, defaults to ContentHashID
+	Src            string
+	Dst            []string
+	T              interface{}    // int64 in units of TimeResolution || time.Time
+	TimeResolution TimeResolution // unit of the timestamp, defaults to milliseconds
+	TTL            int            // the hop limit, defaults to 10 if 0
+	Via            []string       // the hops which forwarded the envelope
+	MaxAge         time.Duration  // sets exp to T plus MaxAge if not 0
+	HashEnvelope   bool           // the id covers data.kind and HashFields too, type tagged for any V
+	HashFields     []string       // the header fields the id covers, e.g. "src", "dst", "ttl"
+	Data           interface{}    // PayloadT1
+	JsonProp       *JsonProps
+	TimeGenerator  TimeGenerator
+	MacKey         []byte // emits a HMAC-SHA256 over the envelope if set
+	MacKeyID       string
+	HashAlgorithm  HashAlgorithm  // defaults to SHA2_256
+	DigestEncoding DigestEncoding // defaults to BASE58
+	V              V              // V_B hashes the data type tagged, defaults to V_A
 }
 
 type SimpleEnvelopeInternal struct {
-	ID       string
-	Src      string
-	Dst      []string
-	T        int64
-	TTL      int
-	Data     PayloadT1
-	JsonProp *JsonProps
+	ID          string
+	IDGenerator IDGenerator
+	Src         string
+	Dst         []string
+	T           int64
+	Tr          TimeResolution
+	TTL         int
+	Via         []string
+	Exp         *int64
+	Hashed      []string
+	Data        Payload[interface{}]
+	JsonProp    *JsonProps
+	MacKey      []byte
+	MacKeyID    string
+	Hash        HashSpec
+	V           V
 }
 
 type JsonHash struct {
@@ -351,20 +712,48 @@ func (*realTimer) Now() time.Time {
 	return time.Now()
 }
 
+// SimpleEnvelope renders its envelope once, the first of AsJson,
+// AsEnvelope, Build, Collect or Sign does the walk. It is safe for
+// concurrent use, the exported fields are set by that walk and must not be
+// changed; Build returns a BuiltEnvelope which has no such fields.
 type SimpleEnvelope struct {
 	simpleEnvelopeProps *SimpleEnvelopeInternal
-	envJsonStrings      []string
+	once                sync.Once
+	done                uint32 // set atomically once the walk is done
+	err                 error
 	envJsonString       *string
-	envJsonC            *JsonCollector
+	dataOnce            sync.Once
+	dataErr             error
+	buildOnce           sync.Once
+	built               *BuiltEnvelope
+	buildErr            error
+	idMu                sync.Mutex
+	id                  string // the id the IDGenerator made
 	Envelope            *EnvelopeT
 	DataJsonHash        *JsonHash
+	Mac                 *Mac
 }
 
 func NewSimpleEnvelope(env *SimpleEnvelopeProps) *SimpleEnvelope {
+	se, err := NewSimpleEnvelopeE(env)
+	if err != nil {
+		panic(err)
+	}
+	return se
+}
+
+func NewSimpleEnvelopeE(env *SimpleEnvelopeProps) (*SimpleEnvelope, error) {
 	var tstmp int64
 	if env.TimeGenerator == nil {
 		env.TimeGenerator = &realTimer{}
 	}
+	tr := env.TimeResolution
+	if tr == "" {
+		tr = TimeResolution_Ms
+	}
+	if _, err := FromTimeResolution(string(tr)); err != nil {
+		return nil, err
+	}
 	switch v := env.T.(type) {
 	case int:
 		tstmp = int64(v)
@@ -374,147 +763,399 @@ func NewSimpleEnvelope(env *SimpleEnvelopeProps) *SimpleEnvelope {
 	case float64:
 		tstmp = int64(v)
 	case time.Time:
-		tstmp = v.UnixMilli()
+		tstmp = tr.Timestamp(v)
 	case nil:
-		tstmp = env.TimeGenerator.Now().UnixMilli()
+		tstmp = tr.Timestamp(env.TimeGenerator.Now())
 	default:
-		panic(fmt.Sprintf("unhandled Type:%t", v))
+		return nil, &UnknownTimestampTypeError{Value: v}
 	}
 
-	payt := PayloadT1{}
+	var payt Payload[interface{}]
 	switch v := env.Data.(type) {
 	case map[string]interface{}:
-		FromDictPayloadT1(v, &payt)
+		p := PayloadT1{}
+		if err := FromDictPayloadT1(v, &p); err != nil {
+			return nil, err
+		}
+		payt = Payload[interface{}]{Kind: p.Kind, Data: p.Data}
 	case PayloadT1:
-		payt = v
+		payt = Payload[interface{}]{Kind: v.Kind, Data: v.Data}
 	case PayloadT:
-		payt = PayloadT1(v)
+		payt = Payload[interface{}]{Kind: v.Kind, Data: v.Data}
+	case payloader:
+		payt = v.payload()
 	default:
-		panic("unhandled Type")
+		return nil, &UnknownPayloadTypeError{Value: v}
+	}
+	version := env.V
+	if version == "" {
+		version = V_A
+	}
+	if _, err := FromV(string(version)); err != nil {
+		return nil, err
+	}
+	ttl := env.TTL
+	if ttl == 0 {
+		ttl = 10
+	}
+	hashed, err := hashedOf(env.HashEnvelope, env.HashFields)
+	if err != nil {
+		return nil, err
+	}
+	idGenerator := env.IDGenerator
+	if idGenerator == nil {
+		idGenerator = ContentHashID{}
 	}
 	sei := SimpleEnvelopeInternal{
-		ID:       env.ID,
-		Src:      env.Src,
-		Dst:      env.Dst,
-		T:        tstmp,
-		TTL:      env.TTL,
-		Data:     payt,
-		JsonProp: env.JsonProp,
-	}
-	se := &SimpleEnvelope{
-		simpleEnvelopeProps: &sei,
-		// envJsonStrings:      make([]string, 1000),
+		ID:          env.ID,
+		IDGenerator: idGenerator,
+		Src:         env.Src,
+		Dst:         env.Dst,
+		T:           tstmp,
+		Tr:          tr,
+		TTL:         ttl,
+		Via:         env.Via,
+		Exp:         expOf(tstmp, tr, env.MaxAge),
+		Hashed:      hashed,
+		Data:        payt,
+		JsonProp:    env.JsonProp,
+		MacKey:      env.MacKey,
+		MacKeyID:    env.MacKeyID,
+		Hash: HashSpec{
+			Algorithm: env.HashAlgorithm,
+			Encoding:  env.DigestEncoding,
+			Tagged:    version == V_B,
+		},
+		V: version,
 	}
-	se.envJsonC = NewJsonCollector(func(part string) {
-		se.envJsonStrings = append(se.envJsonStrings, part)
-	}, se.simpleEnvelopeProps.JsonProp)
-	return se
+	if _, err := sei.Hash.newHash(); err != nil {
+		return nil, err
+	}
+	return &SimpleEnvelope{
+		simpleEnvelopeProps: &sei,
+	}, nil
 }
 
 func (s *SimpleEnvelope) AsDataJson() *string {
-	return s.DataJsonHash.JsonStr
+	str, err := s.AsDataJsonE()
+	if err != nil {
+		panic(err)
+	}
+	return str
 }
 
-func (s *SimpleEnvelope) toDataJson() *JsonHash {
-	var dataJsonStrings []string
+// AsDataJsonE renders the data indented to its place in the envelope, it
+// is not a part of the single walk and costs one of its own.
+func (s *SimpleEnvelope) AsDataJsonE() (*string, error) {
+	if _, err := s.lazy(); err != nil {
+		return nil, err
+	}
+	s.dataOnce.Do(func() {
+		var dataJson bytes.Buffer
+		dataJsonC := NewJsonWriterCollector(&dataJson, s.dataJsonProps())
+		if err := SortKeysE(s.simpleEnvelopeProps.Data.Data, dataJsonC.AppendE); err != nil {
+			s.dataErr = err
+			return
+		}
+		if err := dataJsonC.Flush(); err != nil {
+			s.dataErr = err
+			return
+		}
+		jsonStr := dataJson.String()
+		s.DataJsonHash.JsonStr = &jsonStr
+	})
+	if s.dataErr != nil {
+		return nil, s.dataErr
+	}
+	return s.DataJsonHash.JsonStr, nil
+}
 
+// dataJsonProps indents the data to its place in the envelope
+func (s *SimpleEnvelope) dataJsonProps() *JsonProps {
 	indent := 0
 	if s.simpleEnvelopeProps.JsonProp != nil {
 		indent = s.simpleEnvelopeProps.JsonProp.indent
 	}
-	jpr := NewJsonProps(indent,
+	return NewJsonProps(indent,
 		fmt.Sprintf("\n%v", strings.Repeat(" ", 2*indent)))
-	dataJsonC := NewJsonCollector(func(part string) {
-		dataJsonStrings = append(dataJsonStrings, part)
-	}, jpr)
-	var dataHashC *HashCollector
-	var dataProcessor SvalFn
-	if s.simpleEnvelopeProps.ID != "" {
-		dataProcessor = func(sval SVal) {
-			dataJsonC.Append(sval)
+}
+
+// rendered is what a walk of render computes besides the tokens.
+type rendered struct {
+	envelope *Envelope[interface{}]
+	hash     *string
+	mac      *Mac
+}
+
+// render walks the envelope once. The data tokens feed the content hash,
+// the id follows the data so it is known once the walk reaches it. All
+// tokens of the EnvelopeT go to collectors and, if set, to the mac and
+// envJsonC. The mac sorts before src but covers the fields after it, so
+// envJsonC gets these few header tokens once the walk is done.
+func (s *SimpleEnvelope) render(envJsonC *JsonCollector, collectors ...Collector) (*rendered, error) {
+	props := s.simpleEnvelopeProps
+	envelope := &Envelope[interface{}]{
+		V:      props.V,
+		ID:     props.ID,
+		Src:    props.Src,
+		Dst:    props.Dst,
+		T:      props.T,
+		Tr:     trOf(props.Tr),
+		TTL:    float64(props.TTL),
+		Via:    props.Via,
+		Exp:    props.Exp,
+		Hashed: props.Hashed,
+		Data:   props.Data,
+	}
+
+	dataHashC, err := NewHashCollectorSpec(props.Hash)
+	if err != nil {
+		return nil, err
+	}
+	fanOut := NewFanOut(collectors...)
+	var macC *MacCollector
+	if props.MacKey != nil {
+		macC = NewMacCollector(props.MacKey)
+		fanOut.Add(macC)
+	}
+
+	// the header the id covers follows the id, its digest takes a walk
+	// of its own
+	var envDigest string
+	if props.Hashed != nil {
+		envDigest, err = envelopeHash(envelope, props.Hash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var hash *string
+	var tail []SVal
+	err = SortKeysE(*envelope, func(sval SVal) error {
+		if isDataToken(sval) {
+			if err := dataHashC.AppendE(sval); err != nil {
+				return err
+			}
+		} else if sval.path == "/id" && sval.val != nil {
+			digest := dataHashC.Digest()
+			if props.Hashed != nil {
+				digest = envDigest
+			}
+			hash = &digest
+			if props.ID == "" {
+				id, err := s.mintID(IDInput{
+					T:      props.T,
+					Tr:     props.Tr,
+					Src:    props.Src,
+					Kind:   props.Data.Kind,
+					Digest: digest,
+					Spec:   props.Hash,
+				})
+				if err != nil {
+					return err
+				}
+				envelope.ID = id
+				sval.val = JsonValType{envelope.ID}
+			}
+		}
+		if err := fanOut.AppendE(sval); err != nil {
+			return err
+		}
+		if envJsonC == nil {
+			return nil
+		}
+		if macC != nil && (tail != nil || sval.path == "/src" && sval.attribute != "") {
+			tail = append(tail, sval)
+			return nil
+		}
+		return envJsonC.AppendE(sval)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var mac *Mac
+	if macC != nil {
+		sum, err := macC.Sum()
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		dataHashC = NewHashCollector()
-		dataProcessor = func(sval SVal) {
-			dataHashC.Append(sval)
-			dataJsonC.Append(sval)
+		mac = newMac(props.MacKeyID, sum)
+		if envJsonC != nil {
+			// "mac" sorts right before "src"
+			if err := envJsonC.AppendE(SVal{attribute: "mac", outState: NONE, path: "/mac"}); err != nil {
+				return nil, err
+			}
+			if err := SortKeysE(*mac, envJsonC.AppendE, "/mac"); err != nil {
+				return nil, err
+			}
+			for _, sval := range tail {
+				if err := envJsonC.AppendE(sval); err != nil {
+					return nil, err
+				}
+			}
 		}
 	}
-	SortKeys(s.simpleEnvelopeProps.Data.Data, dataProcessor)
-	var hashVal *string
-	if dataHashC != nil {
-		hash := dataHashC.Digest()
-		hashVal = &hash
+	return &rendered{envelope: envelope, hash: hash, mac: mac}, nil
+}
+
+// mintID makes the id with the IDGenerator on the first walk, the later
+// ones reuse it; ULID or UUIDv7 would make another one each time.
+func (s *SimpleEnvelope) mintID(in IDInput) (string, error) {
+	s.idMu.Lock()
+	defer s.idMu.Unlock()
+	if s.id == "" {
+		id, err := s.simpleEnvelopeProps.IDGenerator.NewID(in)
+		if err != nil {
+			return "", err
+		}
+		s.id = id
 	}
-	jsonStr := strings.Join(dataJsonStrings[:], "")
-	return &JsonHash{
-		JsonStr: &jsonStr,
-		Hash:    hashVal,
+	return s.id, nil
+}
+
+// lazy does the walk of the envelope exactly once and remembers its
+// outcome, errors included. It reports if this call did the walk. An
+// error of collectors is returned to this call only, it does not spoil
+// the rendered envelope.
+func (s *SimpleEnvelope) lazy(collectors ...Collector) (bool, error) {
+	first := false
+	var collectorErr error
+	s.once.Do(func() {
+		first = true
+		apart := &apartCollector{fanOut: NewFanOut(collectors...)}
+		s.err = s.collect(apart)
+		collectorErr = apart.err
+		atomic.StoreUint32(&s.done, 1)
+	})
+	if s.err != nil {
+		return first, s.err
 	}
+	return first, collectorErr
+}
 
+// apartCollector keeps the first error of its collectors to itself, they
+// get no more tokens but the walk goes on.
+type apartCollector struct {
+	fanOut *FanOut
+	err    error
 }
 
-func (s *SimpleEnvelope) lazy() *SimpleEnvelope {
-	s.DataJsonHash = s.toDataJson()
-	t := s.simpleEnvelopeProps.T
-	id := s.simpleEnvelopeProps.ID
-	if id == "" {
-		id = fmt.Sprintf("%v-%v", t, *s.DataJsonHash.Hash)
+func (c *apartCollector) AppendE(sval SVal) error {
+	if c.err == nil {
+		c.err = c.fanOut.AppendE(sval)
 	}
+	return nil
+}
 
-	ttl := s.simpleEnvelopeProps.TTL
-	if ttl == 0 {
-		ttl = 10
+func (s *SimpleEnvelope) collect(collectors ...Collector) error {
+	var envJson bytes.Buffer
+	envJsonC := NewJsonWriterCollector(&envJson, s.simpleEnvelopeProps.JsonProp)
+	r, err := s.render(envJsonC, collectors...)
+	if err != nil {
+		return err
 	}
-	envelope := &EnvelopeT{
-		V:   V_A,
-		ID:  id,
-		Src: s.simpleEnvelopeProps.Src,
-		Dst: s.simpleEnvelopeProps.Dst,
-		T:   float64(t),
-		TTL: float64(ttl),
-		Data: PayloadT1{
-			Kind: s.simpleEnvelopeProps.Data.Kind,
-		},
+	if err := envJsonC.Flush(); err != nil {
+		return err
+	}
+	str := envJson.String()
+	s.envJsonString = &str
+	// typed data is handed out as map, like it reads from the JSON
+	data, isMap := r.envelope.Data.Data.(map[string]interface{})
+	if !isMap {
+		decoded, err := jsonData([]byte(str))
+		if err != nil {
+			return err
+		}
+		data, _ = decoded.(map[string]interface{})
 	}
+	s.Envelope = &EnvelopeT{
+		V:      r.envelope.V,
+		ID:     r.envelope.ID,
+		Src:    r.envelope.Src,
+		Dst:    r.envelope.Dst,
+		T:      r.envelope.T,
+		Tr:     r.envelope.Tr,
+		TTL:    r.envelope.TTL,
+		Via:    r.envelope.Via,
+		Exp:    r.envelope.Exp,
+		Hashed: r.envelope.Hashed,
+		Data:   PayloadT1{Kind: r.envelope.Data.Kind, Data: data},
+	}
+	s.DataJsonHash = &JsonHash{Hash: r.hash}
+	s.Mac = r.mac
+	return nil
+}
 
-	SortKeys(*envelope, func(sval SVal) {
-		oval := sval
-		// /data/date
+// Collect feeds the tokens of the envelope to collectors, e.g. a
+// SignatureCollector. The first walk of the envelope renders its JSON
+// within the same walk, later calls walk again for the collectors only.
+// The "mac" member is part of the JSON only.
+func (s *SimpleEnvelope) Collect(collectors ...Collector) error {
+	first, err := s.lazy(collectors...)
+	if first || err != nil {
+		return err
+	}
+	_, err = s.render(nil, collectors...)
+	return err
+}
 
-		// fmt.Fprintln(os.Stderr, "Path=", sval.path, sval.attribute)
-		if strings.HasSuffix(sval.path, "/data/data") && sval.attribute == "" {
-			// fmt.Fprintln(os.Stderr, "data/data=", sval)
-			if sval.outState.String() == OBJECT_START {
-				oval = SVal{
-					outState: NONE,
-					val:      PlainValType{val: s.AsDataJson()},
-				}
-			} else {
-				return
-			}
+// WriteTo streams the envelope JSON to w in a single walk without keeping
+// the JSON in memory, unless it is rendered already.
+func (s *SimpleEnvelope) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	if atomic.LoadUint32(&s.done) == 1 {
+		if s.err != nil {
+			return 0, s.err
 		}
-		// if sval.val == nil && sval.outState.String() == OBJECT_START {
-		// 	// fmt.Fprintln(os.Stderr, "XXX")
-		// }
-		s.envJsonC.Append(oval)
-	})
-	s.Envelope = envelope
-	s.Envelope.Data = envelope.Data
-	s.Envelope.Data.Data = s.simpleEnvelopeProps.Data.Data
-	// fmt.Fprintln(os.Stderr, s.Envelope)
-	// fmt.Fprintln(os.Stderr, *s.AsDataJson())
-	return s
+		_, err := io.WriteString(counter, *s.envJsonString)
+		return counter.n, err
+	}
+	envJsonC := NewJsonWriterCollector(counter, s.simpleEnvelopeProps.JsonProp)
+	_, err := s.render(envJsonC)
+	if err == nil {
+		err = envJsonC.Flush()
+	}
+	return counter.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
 
 func (s *SimpleEnvelope) AsJson() *string {
-	if s.envJsonString == nil {
-		str := strings.Join(s.lazy().envJsonStrings, "")
-		s.envJsonString = &str
+	str, err := s.AsJsonE()
+	if err != nil {
+		panic(err)
 	}
-	return s.envJsonString
+	return str
+}
+
+func (s *SimpleEnvelope) AsJsonE() (*string, error) {
+	if _, err := s.lazy(); err != nil {
+		return nil, err
+	}
+	return s.envJsonString, nil
 }
 
 func (s *SimpleEnvelope) AsEnvelope() *EnvelopeT {
-	return s.lazy().Envelope
+	env, err := s.AsEnvelopeE()
+	if err != nil {
+		panic(err)
+	}
+	return env
+}
+
+func (s *SimpleEnvelope) AsEnvelopeE() (*EnvelopeT, error) {
+	if _, err := s.lazy(); err != nil {
+		return nil, err
+	}
+	return s.Envelope, nil
 }
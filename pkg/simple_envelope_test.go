@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -39,7 +40,7 @@ func (s *SimpleEnvelopeSuite) TestSimpleTAsLiteral() {
 	n := NewSimpleEnvelope(&props)
 	assert.Equal(s.T(), n.AsEnvelope().Data.Kind, "Kind")
 	assert.Equal(s.T(), n.AsEnvelope().Data.Data, map[string]interface{}{"Hallo": 1})
-	assert.Equal(s.T(), n.AsEnvelope().T, float64(4711))
+	assert.Equal(s.T(), n.AsEnvelope().T, int64(4711))
 }
 
 func (s *SimpleEnvelopeSuite) TestSimpleTAsObj() {
@@ -56,7 +57,7 @@ func (s *SimpleEnvelopeSuite) TestSimpleTAsObj() {
 	n := NewSimpleEnvelope(&props)
 	assert.Equal(s.T(), n.AsEnvelope().Data.Kind, "Kind")
 	assert.Equal(s.T(), n.AsEnvelope().Data.Data, map[string]interface{}{"Hallo": 1})
-	assert.Equal(s.T(), n.AsEnvelope().T, float64(now.UnixMilli()))
+	assert.Equal(s.T(), n.AsEnvelope().T, now.UnixMilli())
 }
 
 func (s *SimpleEnvelopeSuite) TestSortWithOutWithString() {
@@ -239,6 +240,88 @@ func (s *SimpleEnvelopeSuite) TestSortWithOutWithObjOfObj_Y_B_1_A_2() {
 	}}, s.mockedSvalFn.Execute)
 }
 
+func (s *SimpleEnvelopeSuite) jsonOf(e interface{}) (string, error) {
+	out := ""
+	col := NewJsonCollector(func(str string) {
+		out += str
+	}, nil)
+	err := SortKeysE(e, col.AppendE)
+	return out, err
+}
+
+func (s *SimpleEnvelopeSuite) TestSortKeysPointers() {
+	type Obj struct {
+		A *int         `json:"a"`
+		B *Obj         `json:"b"`
+		C interface{}  `json:"c"`
+		D *[]string    `json:"d"`
+		E **string     `json:"e"`
+		F *interface{} `json:"f"`
+	}
+	one := 1
+	str := "x"
+	strp := &str
+	var iface interface{} = &one
+	out, err := s.jsonOf(&Obj{A: &one, B: &Obj{}, C: &one, D: &[]string{"y"}, E: &strp, F: &iface})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"a":1,"b":{"a":null,"b":null,"c":null,"d":null,"e":null,"f":null},"c":1,"d":["y"],"e":"x","f":1}`, out)
+
+	var nilObj *Obj
+	out, err = s.jsonOf(nilObj)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "null", out)
+}
+
+func (s *SimpleEnvelopeSuite) TestSortKeysArraysAndMaps() {
+	type Key string
+	out, err := s.jsonOf(map[string]interface{}{
+		"array":  [3]int{1, 2, 3},
+		"str":    map[string]string{"b": "2", "a": "1"},
+		"int":    map[int]string{10: "x", 9: "y"},
+		"typed":  map[Key][]int{"k": {1}},
+		"nested": map[string]map[string]bool{"o": {"t": true}},
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"array":[1,2,3],"int":{"10":"x","9":"y"},"nested":{"o":{"t":true}},"str":{"a":"1","b":"2"},"typed":{"k":[1]}}`, out)
+}
+
+func (s *SimpleEnvelopeSuite) TestSortKeysPointerHashIsValueHash() {
+	type Obj struct {
+		X int `json:"x"`
+	}
+	h1 := NewHashCollector()
+	SortKeys(Obj{X: 1}, h1.Append)
+	h2 := NewHashCollector()
+	SortKeys(&Obj{X: 1}, h2.Append)
+	h3 := NewHashCollector()
+	SortKeys(map[string]*int{"x": func() *int { i := 1; return &i }()}, h3.Append)
+	assert.Equal(s.T(), h1.Digest(), h2.Digest())
+	assert.Equal(s.T(), h1.Digest(), h3.Digest())
+}
+
+func (s *SimpleEnvelopeSuite) TestSortKeysCycle() {
+	type Node struct {
+		Next *Node `json:"next"`
+	}
+	n := &Node{}
+	n.Next = n
+	_, err := s.jsonOf(n)
+	var uerr *UnmarshalableValueError
+	assert.True(s.T(), errors.As(err, &uerr))
+	assert.Equal(s.T(), "/next", uerr.Path)
+
+	// the same pointer twice is no cycle
+	shared := &Node{}
+	_, err = s.jsonOf([]*Node{shared, shared})
+	assert.NoError(s.T(), err)
+}
+
+func (s *SimpleEnvelopeSuite) TestSortKeysUnsupportedMapKey() {
+	_, err := s.jsonOf(map[float64]int{1.5: 1})
+	var uerr *UnmarshalableValueError
+	assert.True(s.T(), errors.As(err, &uerr))
+}
+
 // #########################
 // ## JSONCollector tests ##
 // #########################
@@ -598,7 +681,7 @@ func (s *SimpleEnvelopeSuite) TestHashCollector_3_InternalUpdate() {
 		Z:    emptySlice,
 		Date: time.UnixMilli(444).UTC(),
 	}
-	collector := &HashCollector{mck}
+	collector := &HashCollector{hash: mck}
 	SortKeys(t, func(prob SVal) {
 		collector.Append(prob)
 	})
@@ -755,6 +838,76 @@ func (s *SimpleEnvelopeSuite) TestMissingDataInEnvelope() {
 	assert.EqualValues(s.T(), yVal.Y, mapVal["y"])
 }
 
+// #################
+// ## error tests ##
+// #################
+func (s *SimpleEnvelopeSuite) TestNewSimpleEnvelopeEUnknownTimestamp() {
+	_, err := NewSimpleEnvelopeE(&SimpleEnvelopeProps{
+		T:    "4711",
+		Data: PayloadT1{Kind: "kind", Data: map[string]interface{}{}},
+	})
+	var terr *UnknownTimestampTypeError
+	assert.True(s.T(), errors.As(err, &terr))
+	assert.Equal(s.T(), "4711", terr.Value)
+	assert.Panics(s.T(), func() {
+		NewSimpleEnvelope(&SimpleEnvelopeProps{T: "4711"})
+	})
+}
+
+func (s *SimpleEnvelopeSuite) TestNewSimpleEnvelopeEUnknownPayload() {
+	_, err := NewSimpleEnvelopeE(&SimpleEnvelopeProps{
+		T:    4711,
+		Data: SampleY{Y: 4},
+	})
+	var perr *UnknownPayloadTypeError
+	assert.True(s.T(), errors.As(err, &perr))
+	assert.Equal(s.T(), SampleY{Y: 4}, perr.Value)
+}
+
+func (s *SimpleEnvelopeSuite) TestAsJsonEUnmarshalableValue() {
+	se, err := NewSimpleEnvelopeE(&SimpleEnvelopeProps{
+		T: 4711,
+		Data: PayloadT1{Kind: "kind", Data: map[string]interface{}{
+			"ch": make(chan int),
+		}},
+	})
+	assert.NoError(s.T(), err)
+	_, err = se.AsJsonE()
+	var uerr *UnmarshalableValueError
+	assert.True(s.T(), errors.As(err, &uerr))
+	assert.Equal(s.T(), "/data/data/ch", uerr.Path)
+	_, err = se.AsEnvelopeE()
+	assert.True(s.T(), errors.As(err, &uerr))
+	assert.Panics(s.T(), func() {
+		se.AsJson()
+	})
+}
+
+func (s *SimpleEnvelopeSuite) TestSortKeysEUnexportedField() {
+	out, err := s.jsonOf(struct {
+		Y struct {
+			x int
+			X int
+		} `json:"y"`
+	}{})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), `{"y":{"X":0}}`, out)
+}
+
+func (s *SimpleEnvelopeSuite) TestSortKeysEStopsOnCollectorError() {
+	stop := errors.New("stop")
+	calls := 0
+	err := SortKeysE([]int{1, 2, 3}, func(SVal) error {
+		calls++
+		if calls == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(s.T(), stop, err)
+	assert.Equal(s.T(), 2, calls)
+}
+
 func TestSimpleEnvelopeSuite(t *testing.T) {
 	suite.Run(t, new(SimpleEnvelopeSuite))
 }
@@ -0,0 +1,130 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Tags of the type tagged hash encoding used by V_B envelopes. Every token
+// is written as its tag byte, the payload length as 8 byte big endian and
+// the payload itself, so neither the boundaries nor the types of two
+// tokens can be mixed up.
+const (
+	TAG_ARRAY_START  = '['
+	TAG_ARRAY_END    = ']'
+	TAG_OBJECT_START = '{'
+	TAG_OBJECT_END   = '}'
+	TAG_KEY          = 'k'
+	TAG_STRING       = 's'
+	TAG_NUMBER       = 'n'
+	TAG_BOOLEAN      = 'b'
+	TAG_NULL         = 'z'
+)
+
+func (h *HashCollector) writeTagged(tag byte, payload string) error {
+	var head [9]byte
+	head[0] = tag
+	binary.BigEndian.PutUint64(head[1:], uint64(len(payload)))
+	if _, err := h.hash.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := h.hash.Write([]byte(payload))
+	return err
+}
+
+func (h *HashCollector) appendTagged(sval SVal) error {
+	switch sval.outState {
+	case ARRAY_START:
+		return h.writeTagged(TAG_ARRAY_START, "")
+	case ARRAY_END:
+		return h.writeTagged(TAG_ARRAY_END, "")
+	case OBJECT_START:
+		return h.writeTagged(TAG_OBJECT_START, "")
+	case OBJECT_END:
+		return h.writeTagged(TAG_OBJECT_END, "")
+	}
+	if sval.attribute != "" {
+		if err := h.writeTagged(TAG_KEY, sval.attribute); err != nil {
+			return err
+		}
+	}
+	if sval.val == nil {
+		return nil
+	}
+	vl := sval.val.AsValue()
+	switch v := vl.(type) {
+	case nil:
+		return h.writeTagged(TAG_NULL, "")
+	case string:
+		return h.writeTagged(TAG_STRING, v)
+	case bool:
+		if v {
+			return h.writeTagged(TAG_BOOLEAN, "true")
+		}
+		return h.writeTagged(TAG_BOOLEAN, "false")
+	case time.Time:
+		return h.writeTagged(TAG_STRING, v.Format(JSISOStringFormat))
+	case json.Number:
+		n, err := canonicalNumber(v)
+		if err != nil {
+			return &UnmarshalableValueError{Path: sval.path, Err: err}
+		}
+		return h.writeTagged(TAG_NUMBER, n)
+	}
+	b, err := json.Marshal(vl)
+	if err != nil {
+		return &UnmarshalableValueError{Path: sval.path, Err: err}
+	}
+	if isJsonScalar(vl) {
+		return h.writeTagged(TAG_NUMBER, string(b))
+	}
+	if reflect.ValueOf(vl).Kind() == reflect.String {
+		return h.writeTagged(TAG_STRING, reflect.ValueOf(vl).String())
+	}
+	// anything else is hashed the way it reads back from its JSON form
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return &UnmarshalableValueError{Path: sval.path, Err: err}
+	}
+	return SortKeysE(decoded, h.appendTagged, sval.path)
+}
+
+// canonicalNumber writes a parsed number like Go writes the number it
+// stands for, so 1.0 and 1e3 of another producer hash like 1 and 1000.
+// Integers which fit an int64 keep all their digits.
+func canonicalNumber(n json.Number) (string, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return "", err
+	}
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return strconv.FormatInt(int64(f), 10), nil
+	}
+	b, err := json.Marshal(f)
+	return string(b), err
+}
+
+// isJsonScalar reports the values JSON writes as a number or null.
+func isJsonScalar(vl interface{}) bool {
+	switch vl.(type) {
+	case nil, json.Number:
+		return true
+	}
+	switch reflect.TypeOf(vl).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TaggedHashSuite struct {
+	suite.Suite
+}
+
+func (s *TaggedHashSuite) digest(tagged bool, val interface{}) string {
+	h, err := NewHashCollectorSpec(HashSpec{Tagged: tagged})
+	assert.NoError(s.T(), err)
+	SortKeys(val, h.Append)
+	return h.Digest()
+}
+
+func (s *TaggedHashSuite) assertCollision(a, b interface{}) {
+	assert.Equal(s.T(), s.digest(false, a), s.digest(false, b))
+	assert.NotEqual(s.T(), s.digest(true, a), s.digest(true, b))
+}
+
+func (s *TaggedHashSuite) TestKeyValueBoundary() {
+	s.assertCollision(
+		map[string]interface{}{"ab": "c"},
+		map[string]interface{}{"a": "bc"})
+}
+
+func (s *TaggedHashSuite) TestStringNumber() {
+	s.assertCollision(
+		map[string]interface{}{"a": "1"},
+		map[string]interface{}{"a": 1})
+	s.assertCollision(
+		map[string]interface{}{"a": "true"},
+		map[string]interface{}{"a": true})
+}
+
+func (s *TaggedHashSuite) TestStructure() {
+	s.assertCollision(
+		map[string]interface{}{"a": []interface{}{}},
+		map[string]interface{}{"a": map[string]interface{}{}})
+	s.assertCollision(
+		[]interface{}{[]interface{}{"a"}},
+		[]interface{}{"a"})
+	s.assertCollision(
+		[]interface{}{"a", "b"},
+		[]interface{}{"ab"})
+	s.assertCollision(
+		map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": 2},
+		map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}})
+}
+
+func (s *TaggedHashSuite) TestNumbersAreCanonical() {
+	assert.Equal(s.T(),
+		s.digest(true, map[string]interface{}{"y": 1624140000000}),
+		s.digest(true, map[string]interface{}{"y": float64(1624140000000)}))
+	for literal, expected := range map[string]interface{}{
+		"1.0":               1,
+		"1e3":               1000,
+		"1E+3":              float64(1000),
+		"-2.50":             -2.5,
+		"0.000001e1":        0.00001,
+		"1e21":              1e21,
+		"9007199254740993":  int64(9007199254740993),
+		"1624140000000.000": int64(1624140000000),
+	} {
+		assert.Equal(s.T(),
+			s.digest(true, map[string]interface{}{"y": expected}),
+			s.digest(true, map[string]interface{}{"y": json.Number(literal)}), literal)
+	}
+}
+
+func (s *TaggedHashSuite) TestVector() {
+	h, err := NewHashCollectorSpec(HashSpec{Encoding: HEX, Tagged: true})
+	assert.NoError(s.T(), err)
+	SortKeys(map[string]interface{}{"a": []interface{}{1, "1", true, nil}}, h.Append)
+	assert.Equal(s.T(), "f1220f72b4f322c07bb4881b1f566d8c9a091e87916aff620a4fbc463d9e23489738f", h.Digest())
+}
+
+func (s *TaggedHashSuite) TestEnvelopeVB() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"ab": "c", "y": 1624140000000}},
+		TimeGenerator: mtimer,
+		V:             V_B,
+	})
+	js := *se.AsJson()
+	assert.Contains(s.T(), js, `"v":"B"`)
+	_, err := ParseSimpleEnvelope([]byte(js))
+	assert.NoError(s.T(), err)
+
+	var merr *IDMismatchError
+	_, err = ParseSimpleEnvelope([]byte(strings.Replace(js, `"ab":"c"`, `"a":"bc"`, 1)))
+	assert.True(s.T(), errors.As(err, &merr))
+	// a V_B id does not verify as V_A
+	_, err = ParseSimpleEnvelope([]byte(strings.Replace(js, `"v":"B"`, `"v":"A"`, 1)))
+	assert.True(s.T(), errors.As(err, &merr))
+}
+
+func (s *TaggedHashSuite) TestEnvelopeHashIsTagged() {
+	id := func(dst []string, data map[string]interface{}) string {
+		return NewSimpleEnvelope(&SimpleEnvelopeProps{
+			Src:           "test case",
+			Dst:           dst,
+			HashFields:    []string{"dst", "src"},
+			TimeGenerator: mtimer,
+			Data:          PayloadT1{Kind: "test", Data: data},
+		}).AsEnvelope().ID
+	}
+	data := map[string]interface{}{"a": "b"}
+	assert.NotEqual(s.T(), id([]string{"a", "b"}, data), id([]string{"ab"}, data))
+	assert.NotEqual(s.T(), id([]string{}, data), id([]string{}, map[string]interface{}{"ab": ""}))
+
+	js := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{"a", "b"},
+		HashFields:    []string{"dst", "src"},
+		TimeGenerator: mtimer,
+		Data:          PayloadT1{Kind: "test", Data: data},
+	}).AsJson()
+	assert.Contains(s.T(), *js, `"v":"A"`)
+	_, err := ParseSimpleEnvelope([]byte(*js))
+	assert.NoError(s.T(), err)
+	var merr *IDMismatchError
+	_, err = ParseSimpleEnvelope([]byte(strings.Replace(*js, `"dst":["a","b"]`, `"dst":["ab"]`, 1)))
+	assert.True(s.T(), errors.As(err, &merr), err)
+}
+
+func (s *TaggedHashSuite) TestUnknownVersion() {
+	_, err := NewSimpleEnvelopeE(&SimpleEnvelopeProps{
+		Data: PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+		V:    "C",
+	})
+	assert.Error(s.T(), err)
+}
+
+func TestTaggedHashSuite(t *testing.T) {
+	suite.Run(t, new(TaggedHashSuite))
+}
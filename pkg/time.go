@@ -0,0 +1,86 @@
+package c5
+
+import (
+	"time"
+)
+
+// resolution returns the unit of tr, milliseconds if tr is nil
+func resolution(tr *TimeResolution) TimeResolution {
+	if tr == nil {
+		return TimeResolution_Ms
+	}
+	return *tr
+}
+
+// Timestamp turns t into the integer of its unit since 1970 UTC.
+func (tr TimeResolution) Timestamp(t time.Time) int64 {
+	switch tr {
+	case TimeResolution_Us:
+		return t.UnixMicro()
+	case TimeResolution_Ns:
+		return t.UnixNano()
+	}
+	return t.UnixMilli()
+}
+
+// Time turns the timestamp t of the unit tr into a local time.Time, like
+// time.UnixMilli does.
+func (tr TimeResolution) Time(t int64) time.Time {
+	switch tr {
+	case TimeResolution_Us:
+		return time.UnixMicro(t)
+	case TimeResolution_Ns:
+		return time.Unix(0, t)
+	}
+	return time.UnixMilli(t)
+}
+
+// Unit is the duration of one tick of tr.
+func (tr TimeResolution) Unit() time.Duration {
+	switch tr {
+	case TimeResolution_Us:
+		return time.Microsecond
+	case TimeResolution_Ns:
+		return time.Nanosecond
+	}
+	return time.Millisecond
+}
+
+// expOf returns the exp field of an envelope of time t which expires
+// after maxAge, nil for 0
+func expOf(t int64, tr TimeResolution, maxAge time.Duration) *int64 {
+	if maxAge == 0 {
+		return nil
+	}
+	exp := t + int64(maxAge/tr.Unit())
+	return &exp
+}
+
+// trOf returns the tr field of an envelope of the unit tr, nil for
+// milliseconds, which is the default and keeps the JSON of older
+// envelopes.
+func trOf(tr TimeResolution) *TimeResolution {
+	if tr == "" || tr == TimeResolution_Ms {
+		return nil
+	}
+	return &tr
+}
+
+// Time returns T in its resolution as time.Time.
+func (r *EnvelopeT) Time() time.Time {
+	return resolution(r.Tr).Time(r.T)
+}
+
+// Time returns T in its resolution as time.Time.
+func (r *Envelope[T]) Time() time.Time {
+	return resolution(r.Tr).Time(r.T)
+}
+
+// Expires returns Exp in its resolution as time.Time, false if there is
+// none.
+func (r *EnvelopeT) Expires() (time.Time, bool) {
+	if r.Exp == nil {
+		return time.Time{}, false
+	}
+	return resolution(r.Tr).Time(*r.Exp), true
+}
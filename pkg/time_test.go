@@ -0,0 +1,103 @@
+package c5
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimeSuite struct {
+	suite.Suite
+}
+
+// nanos does not fit into a float64
+const nanos = int64(1624140000123456789)
+
+func (s *TimeSuite) envelope(tr TimeResolution, t interface{}) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:            "test case",
+		Dst:            []string{},
+		T:              t,
+		TimeResolution: tr,
+		Data:           PayloadT1{Kind: "test", Data: map[string]interface{}{"date": "2021-05-20", "name": "object"}},
+	})
+}
+
+func (s *TimeSuite) TestResolutions() {
+	at := time.Unix(0, nanos)
+	for tr, expected := range map[TimeResolution]int64{
+		TimeResolution_Ms: 1624140000123,
+		TimeResolution_Us: 1624140000123456,
+		TimeResolution_Ns: nanos,
+	} {
+		assert.Equal(s.T(), expected, tr.Timestamp(at), tr)
+		assert.Equal(s.T(), expected, tr.Timestamp(tr.Time(expected)), tr)
+		env := s.envelope(tr, at).AsEnvelope()
+		assert.Equal(s.T(), expected, env.T, tr)
+		assert.Equal(s.T(), tr.Timestamp(at), tr.Timestamp(env.Time()), tr)
+	}
+}
+
+func (s *TimeSuite) TestDefaultKeepsJson() {
+	se := s.envelope("", int64(1624140000000))
+	assert.NotContains(s.T(), *se.AsJson(), `"tr"`)
+	assert.Equal(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", se.AsEnvelope().ID)
+	assert.Equal(s.T(), *se.AsJson(), *s.envelope(TimeResolution_Ms, int64(1624140000000)).AsJson())
+	assert.Nil(s.T(), se.AsEnvelope().Tr)
+}
+
+func (s *TimeSuite) TestNanosAreLossless() {
+	se := s.envelope(TimeResolution_Ns, nanos)
+	envJson := *se.AsJson()
+	assert.Contains(s.T(), envJson, `"t":1624140000123456789`)
+	assert.Contains(s.T(), envJson, `"tr":"ns"`)
+	assert.True(s.T(), strings.HasPrefix(se.AsEnvelope().ID, "1624140000123456789-"))
+
+	parsed, err := ParseSimpleEnvelope([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), envJson, *parsed.AsJson())
+
+	env, err := UnmarshalEnvelopeT([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), nanos, env.T)
+	assert.Equal(s.T(), TimeResolution_Ns, *env.Tr)
+	assert.Equal(s.T(), time.Unix(0, nanos), env.Time())
+	assert.NoError(s.T(), VerifyEnvelopeT(env))
+
+	typed, err := ParseEnvelope[map[string]interface{}]([]byte(envJson))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), nanos, typed.T)
+	assert.Equal(s.T(), time.Unix(0, nanos), typed.Time())
+
+	b, err := se.Build()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), nanos, b.Timestamp())
+	assert.Equal(s.T(), TimeResolution_Ns, b.TimeResolution())
+	assert.Equal(s.T(), time.Unix(0, nanos), b.Time())
+}
+
+func (s *TimeSuite) TestTimeGenerator() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		TimeResolution: TimeResolution_Us,
+		TimeGenerator:  mtimer,
+		Data:           PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+	})
+	assert.Equal(s.T(), mtimer.Now().UnixMicro(), se.AsEnvelope().T)
+}
+
+func (s *TimeSuite) TestUnknownResolution() {
+	_, err := NewSimpleEnvelopeE(&SimpleEnvelopeProps{
+		TimeResolution: "s",
+		Data:           PayloadT1{Kind: "test", Data: map[string]interface{}{}},
+	})
+	assert.Error(s.T(), err)
+	_, err = UnmarshalEnvelopeT([]byte(`{"data":{"data":{},"kind":"test"},"dst":[],"id":"x","src":"","t":1,"tr":"s","ttl":10,"v":"A"}`))
+	assert.Error(s.T(), err)
+}
+
+func TestTimeSuite(t *testing.T) {
+	suite.Run(t, new(TimeSuite))
+}
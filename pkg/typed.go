@@ -0,0 +1,133 @@
+package c5
+
+import (
+	"encoding/json"
+)
+
+// Payload is PayloadT1 with typed data, like Payload<T> in
+// schema/payload.ts. SimpleEnvelopeProps.Data takes it as is, the data is
+// walked directly, so a struct hashes like the map its ToDict returns.
+type Payload[T any] struct {
+	Data T      `json:"data"`
+	Kind string `json:"kind"`
+}
+
+// payloader lets NewSimpleEnvelopeE take a Payload of any type
+type payloader interface {
+	payload() Payload[interface{}]
+}
+
+func (p Payload[T]) payload() Payload[interface{}] {
+	return Payload[interface{}]{Kind: p.Kind, Data: p.Data}
+}
+
+// Envelope is EnvelopeT with typed data, like Envelope<T> in
+// schema/envelope.ts.
+type Envelope[T any] struct {
+	Data   Payload[T]      `json:"data"`
+	Dst    []string        `json:"dst"`
+	Exp    *int64          `json:"exp,omitempty"`
+	Hashed []string        `json:"hashed,omitempty"`
+	ID     string          `json:"id"`
+	Src    string          `json:"src"`
+	T      int64           `json:"t"`
+	Tr     *TimeResolution `json:"tr,omitempty"`
+	TTL    float64         `json:"ttl"`
+	Via    []string        `json:"via,omitempty"`
+	V      V               `json:"v"`
+}
+
+func (r *Envelope[T]) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// UnmarshalEnvelope decodes an envelope into typed data, the id is not
+// checked, use ParseEnvelope for that.
+func UnmarshalEnvelope[T any](b []byte) (*Envelope[T], error) {
+	env := &Envelope[T]{}
+	if err := json.Unmarshal(b, env); err != nil {
+		return nil, err
+	}
+	if _, err := FromV(string(env.V)); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// ParseEnvelope decodes an envelope into typed data and verifies its id
+// against the data as it reads in the JSON.
+func ParseEnvelope[T any](b []byte, opts ...ParseOption) (*Envelope[T], error) {
+	env, err := UnmarshalEnvelope[T](b)
+	if err != nil {
+		return nil, err
+	}
+	data, err := jsonData(b)
+	if err != nil {
+		return nil, err
+	}
+	o := newParseOptions(opts)
+	header := env.header()
+	if err := verifyID(o.idGenerator, header, data); err != nil {
+		return nil, err
+	}
+	if err := o.check(header, data); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// header returns the fields of r the checks look at, without data
+func (r *Envelope[T]) header() *EnvelopeT {
+	return &EnvelopeT{
+		V:      r.V,
+		ID:     r.ID,
+		Src:    r.Src,
+		Dst:    r.Dst,
+		T:      r.T,
+		Tr:     r.Tr,
+		TTL:    r.TTL,
+		Via:    r.Via,
+		Exp:    r.Exp,
+		Hashed: r.Hashed,
+		Data:   PayloadT1{Kind: r.Data.Kind},
+	}
+}
+
+// Verify checks the id of the envelope against its typed data, as the
+// generator its shape tells makes it unless WithIDGenerator says
+// otherwise.
+func (r *Envelope[T]) Verify(opts ...ParseOption) error {
+	return verifyID(newParseOptions(opts).idGenerator, r.header(), r.Data.Data)
+}
+
+// AsEnvelopeOf returns the envelope of s with data of type T. Data which
+// was given as T is returned as is, anything else is decoded from the
+// JSON of the envelope.
+func AsEnvelopeOf[T any](s *SimpleEnvelope) (*Envelope[T], error) {
+	envJson, err := s.AsJsonE()
+	if err != nil {
+		return nil, err
+	}
+	env := s.Envelope
+	data, ok := s.simpleEnvelopeProps.Data.Data.(T)
+	if !ok {
+		decoded, err := UnmarshalEnvelope[T]([]byte(*envJson))
+		if err != nil {
+			return nil, err
+		}
+		data = decoded.Data.Data
+	}
+	return &Envelope[T]{
+		Data:   Payload[T]{Kind: env.Data.Kind, Data: data},
+		Dst:    env.Dst,
+		Exp:    env.Exp,
+		Hashed: env.Hashed,
+		ID:     env.ID,
+		Src:    env.Src,
+		T:      env.T,
+		Tr:     env.Tr,
+		TTL:    env.TTL,
+		Via:    env.Via,
+		V:      env.V,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package c5
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TypedSuite struct {
+	suite.Suite
+}
+
+type order struct {
+	Customer string   `json:"customer"`
+	Items    []item   `json:"items"`
+	Note     *string  `json:"note,omitempty"`
+	Tags     []string `json:"tags"`
+}
+
+type item struct {
+	Sku   string  `json:"sku"`
+	Count float64 `json:"count"`
+}
+
+func (s *TypedSuite) props(data interface{}, v V) *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{},
+		Data:          data,
+		TimeGenerator: mtimer,
+		V:             v,
+	}
+}
+
+func (s *TypedSuite) TestSameHashAsMap() {
+	y := SampleY{Y: 4}
+	nd := SampleNameDate{Name: "object", Date: "2021-05-20"}
+	for _, v := range []V{V_A, V_B} {
+		typed := NewSimpleEnvelope(s.props(Payload[SampleY]{Kind: "y", Data: y}, v))
+		mapped := NewSimpleEnvelope(s.props(PayloadT1{Kind: "y", Data: y.ToDict()}, v))
+		assert.Equal(s.T(), *mapped.AsJson(), *typed.AsJson())
+
+		typed = NewSimpleEnvelope(s.props(Payload[*SampleNameDate]{Kind: "nd", Data: &nd}, v))
+		mapped = NewSimpleEnvelope(s.props(PayloadT1{Kind: "nd", Data: nd.ToDict()}, v))
+		assert.Equal(s.T(), *mapped.AsJson(), *typed.AsJson())
+	}
+	typed := NewSimpleEnvelope(s.props(Payload[SampleNameDate]{Kind: "test", Data: nd}, V_A))
+	assert.Equal(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", typed.AsEnvelope().ID)
+}
+
+func (s *TypedSuite) TestNestedStruct() {
+	o := order{
+		Customer: "c1",
+		Items:    []item{{Sku: "a", Count: 2}, {Sku: "b", Count: 1.5}},
+		Tags:     []string{"x"},
+	}
+	mapped := map[string]interface{}{
+		"customer": "c1",
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a", "count": 2.0},
+			map[string]interface{}{"sku": "b", "count": 1.5},
+		},
+		"tags": []interface{}{"x"},
+	}
+	for _, v := range []V{V_A, V_B} {
+		typed := NewSimpleEnvelope(s.props(Payload[order]{Kind: "order", Data: o}, v))
+		plain := NewSimpleEnvelope(s.props(PayloadT1{Kind: "order", Data: mapped}, v))
+		assert.Equal(s.T(), *plain.AsJson(), *typed.AsJson())
+		assert.NoError(s.T(), typed.Verify())
+	}
+}
+
+func (s *TypedSuite) TestRoundTrip() {
+	nd := SampleNameDate{Name: "object", Date: "2021-05-20"}
+	se := NewSimpleEnvelope(s.props(Payload[SampleNameDate]{Kind: "test", Data: nd}, V_B))
+	env, err := ParseEnvelope[SampleNameDate]([]byte(*se.AsJson()))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), nd, env.Data.Data)
+	assert.Equal(s.T(), "test", env.Data.Kind)
+	assert.Equal(s.T(), se.AsEnvelope().ID, env.ID)
+	assert.NoError(s.T(), env.Verify())
+
+	b, err := env.Marshal()
+	assert.NoError(s.T(), err)
+	again, err := ParseEnvelope[SampleNameDate](b)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), env, again)
+}
+
+func (s *TypedSuite) TestParseTampered() {
+	se := NewSimpleEnvelope(s.props(Payload[SampleY]{Kind: "y", Data: SampleY{Y: 4}}, V_A))
+	env, err := UnmarshalEnvelope[SampleY]([]byte(*se.AsJson()))
+	assert.NoError(s.T(), err)
+	env.Data.Data.Y = 5
+	b, err := env.Marshal()
+	assert.NoError(s.T(), err)
+	_, err = ParseEnvelope[SampleY](b)
+	var merr *IDMismatchError
+	assert.True(s.T(), errors.As(err, &merr))
+	assert.True(s.T(), errors.As(env.Verify(), &merr))
+}
+
+func (s *TypedSuite) TestAsEnvelopeOf() {
+	o := order{Customer: "c1", Items: []item{{Sku: "a", Count: 2}}, Tags: []string{}}
+	se := NewSimpleEnvelope(s.props(Payload[order]{Kind: "order", Data: o}, V_A))
+	env, err := AsEnvelopeOf[order](se)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), o, env.Data.Data)
+	assert.Equal(s.T(), se.AsEnvelope().ID, env.ID)
+	// the untyped view reads like the JSON
+	assert.Equal(s.T(), "c1", se.AsEnvelope().Data.Data["customer"])
+
+	y := SampleY{Y: 4}
+	se = NewSimpleEnvelope(s.props(PayloadT1{Kind: "y", Data: y.ToDict()}, V_A))
+	envY, err := AsEnvelopeOf[SampleY](se)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), y, envY.Data.Data)
+	assert.NoError(s.T(), envY.Verify())
+}
+
+func TestTypedSuite(t *testing.T) {
+	suite.Run(t, new(TypedSuite))
+}
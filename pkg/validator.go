@@ -0,0 +1,362 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// schemaFrame is an object or array the SchemaCollector is in
+type schemaFrame struct {
+	schema *Schema // nil accepts anything
+	path   string
+	object bool
+	attr   string
+	keys   map[string]bool
+	items  int
+	// the JSON of the value, if an enum has to compare it
+	captured *bytes.Buffer
+	capture  *JsonCollector
+}
+
+// SchemaCollector validates the tokens of /data/data against a schema,
+// so it can run within the walk which renders the envelope. Walk plain
+// data with the path "/data/data" to get the same paths.
+type SchemaCollector struct {
+	schema     *Schema
+	stack      []*schemaFrame
+	violations []SchemaViolation
+}
+
+func NewSchemaCollector(schema *Schema) *SchemaCollector {
+	return &SchemaCollector{schema: schema}
+}
+
+// Violations lists the mismatches found so far.
+func (c *SchemaCollector) Violations() []SchemaViolation {
+	return c.violations
+}
+
+// Err returns a ValidationError for kind if there are violations.
+func (c *SchemaCollector) Err(kind string) error {
+	if len(c.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Kind: kind, Violations: c.violations}
+}
+
+func (c *SchemaCollector) violate(path string, keyword string, format string, args ...interface{}) {
+	c.violations = append(c.violations, SchemaViolation{
+		Path:    path,
+		Keyword: keyword,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *SchemaCollector) AppendE(sval SVal) error {
+	if !isDataToken(sval) {
+		return nil
+	}
+	switch {
+	case sval.outState == OBJECT_START || sval.outState == ARRAY_START:
+		object := sval.outState == OBJECT_START
+		schema := c.child(sval.path)
+		if schema != nil {
+			typ := "array"
+			if object {
+				typ = "object"
+			}
+			c.checkType(schema, sval.path, typ, false)
+		}
+		frame := &schemaFrame{schema: schema, path: sval.path, object: object, keys: map[string]bool{}}
+		if schema != nil && len(schema.enum) > 0 {
+			frame.captured = &bytes.Buffer{}
+			frame.capture = NewJsonWriterCollector(frame.captured, nil)
+		}
+		c.stack = append(c.stack, frame)
+		return c.forward(sval)
+	case sval.outState == OBJECT_END || sval.outState == ARRAY_END:
+		if err := c.forward(sval); err != nil {
+			return err
+		}
+		frame := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		return c.close(frame)
+	case sval.val != nil:
+		str, err := sval.val.ToStringE()
+		if err != nil {
+			return &UnmarshalableValueError{Path: sval.path, Err: err}
+		}
+		if schema := c.child(sval.path); schema != nil {
+			c.checkScalar(schema, sval.path, *str)
+		}
+		return c.forward(sval)
+	case sval.attribute != "":
+		if len(c.stack) > 0 {
+			c.stack[len(c.stack)-1].attr = sval.attribute
+		}
+		return c.forward(sval)
+	}
+	return nil
+}
+
+// forward passes sval to the frames which capture their JSON
+func (c *SchemaCollector) forward(sval SVal) error {
+	for _, frame := range c.stack {
+		if frame.capture != nil {
+			if err := frame.capture.AppendE(sval); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// child returns the schema of the value at path, nil if anything goes
+func (c *SchemaCollector) child(path string) *Schema {
+	var schema *Schema
+	if len(c.stack) == 0 {
+		schema = c.schema
+	} else {
+		parent := c.stack[len(c.stack)-1]
+		if parent.schema == nil {
+			return nil
+		}
+		if parent.object {
+			parent.keys[parent.attr] = true
+			if sub, found := parent.schema.properties[parent.attr]; found {
+				schema = sub
+			} else if parent.schema.noAdditional {
+				c.violate(path, "additionalProperties", "property '%v' is not allowed", parent.attr)
+				return nil
+			} else {
+				schema = parent.schema.additional
+			}
+		} else {
+			parent.items++
+			schema = parent.schema.items
+		}
+	}
+	if schema != nil && schema.never {
+		c.violate(path, "false", "no value is allowed")
+		return nil
+	}
+	return schema
+}
+
+func (c *SchemaCollector) close(frame *schemaFrame) error {
+	schema := frame.schema
+	if schema == nil {
+		return nil
+	}
+	if frame.object {
+		for _, name := range schema.required {
+			if !frame.keys[name] {
+				c.violate(frame.path, "required", "missing required property '%v'", name)
+			}
+		}
+	} else {
+		if schema.minItems != nil && frame.items < *schema.minItems {
+			c.violate(frame.path, "minItems", "expected at least %d items, got %d", *schema.minItems, frame.items)
+		}
+		if schema.maxItems != nil && frame.items > *schema.maxItems {
+			c.violate(frame.path, "maxItems", "expected at most %d items, got %d", *schema.maxItems, frame.items)
+		}
+	}
+	if frame.capture != nil {
+		if err := frame.capture.Flush(); err != nil {
+			return err
+		}
+		if !enumContainsJson(schema.enum, frame.captured.String()) {
+			c.violate(frame.path, "enum", "value is not one of the enum")
+		}
+	}
+	return nil
+}
+
+// jsonType names the JSON type of the JSON text of a scalar
+func jsonType(str string) string {
+	switch str[0] {
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+func (c *SchemaCollector) checkType(schema *Schema, path string, typ string, integral bool) bool {
+	if len(schema.types) == 0 {
+		return true
+	}
+	for _, t := range schema.types {
+		if t == typ || (t == "integer" && typ == "number" && integral) {
+			return true
+		}
+	}
+	c.violate(path, "type", "expected %v, got %v", joinTypes(schema.types), typ)
+	return false
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("one of %v", types)
+}
+
+func (c *SchemaCollector) checkScalar(schema *Schema, path string, str string) {
+	typ := jsonType(str)
+	var num float64
+	integral := false
+	if typ == "number" {
+		var err error
+		num, err = strconv.ParseFloat(str, 64)
+		if err != nil {
+			c.violate(path, "type", "'%v' is not a number", str)
+			return
+		}
+		integral = num == math.Trunc(num) && !math.IsInf(num, 0)
+	}
+	if !c.checkType(schema, path, typ, integral) {
+		return
+	}
+	if len(schema.enum) > 0 && !enumContainsJson(schema.enum, str) {
+		c.violate(path, "enum", "%v is not one of the enum", str)
+	}
+	switch typ {
+	case "string":
+		var val string
+		if err := json.Unmarshal([]byte(str), &val); err != nil {
+			c.violate(path, "type", "'%v' is not a string", str)
+			return
+		}
+		c.checkString(schema, path, val)
+	case "number":
+		c.checkNumber(schema, path, num)
+	}
+}
+
+func (c *SchemaCollector) checkString(schema *Schema, path string, val string) {
+	runes := utf8.RuneCountInString(val)
+	if schema.minLength != nil && runes < *schema.minLength {
+		c.violate(path, "minLength", "expected at least %d characters, got %d", *schema.minLength, runes)
+	}
+	if schema.maxLength != nil && runes > *schema.maxLength {
+		c.violate(path, "maxLength", "expected at most %d characters, got %d", *schema.maxLength, runes)
+	}
+	if schema.pattern != nil && !schema.pattern.MatchString(val) {
+		c.violate(path, "pattern", "'%v' does not match '%v'", val, schema.pattern)
+	}
+}
+
+func (c *SchemaCollector) checkNumber(schema *Schema, path string, num float64) {
+	if schema.minimum != nil && num < *schema.minimum {
+		c.violate(path, "minimum", "%v is less than %v", num, *schema.minimum)
+	}
+	if schema.maximum != nil && num > *schema.maximum {
+		c.violate(path, "maximum", "%v is greater than %v", num, *schema.maximum)
+	}
+	if schema.exclMinimum != nil && num <= *schema.exclMinimum {
+		c.violate(path, "exclusiveMinimum", "%v is not greater than %v", num, *schema.exclMinimum)
+	}
+	if schema.exclMaximum != nil && num >= *schema.exclMaximum {
+		c.violate(path, "exclusiveMaximum", "%v is not less than %v", num, *schema.exclMaximum)
+	}
+}
+
+// enumContainsJson compares the JSON text of a value with the enum,
+// numbers by value and anything else by its compact JSON with sorted keys.
+func enumContainsJson(enum []interface{}, str string) bool {
+	num, numErr := strconv.ParseFloat(str, 64)
+	for _, entry := range enum {
+		if n, ok := entry.(json.Number); ok {
+			f, err := n.Float64()
+			if err == nil && numErr == nil && f == num {
+				return true
+			}
+			continue
+		}
+		var out bytes.Buffer
+		jsonC := NewJsonWriterCollector(&out, nil)
+		if SortKeysE(entry, jsonC.AppendE) == nil && jsonC.Flush() == nil && out.String() == str {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator holds the schemas of payload kinds. It is safe for concurrent
+// use.
+type Validator struct {
+	mu      sync.RWMutex
+	schemas map[string]*Schema
+	// RejectUnknownKinds fails kinds without schema with an
+	// UnknownKindError instead of letting them pass
+	RejectUnknownKinds bool
+}
+
+func NewValidator() *Validator {
+	return &Validator{
+		schemas: map[string]*Schema{},
+	}
+}
+
+// AddSchema compiles the JSON Schema document doc for kind.
+func (v *Validator) AddSchema(kind string, doc []byte) error {
+	schema, err := CompileSchema(doc)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.schemas[kind] = schema
+	return nil
+}
+
+// AddSchemaFile loads the JSON Schema document at path for kind.
+func (v *Validator) AddSchemaFile(kind string, path string) error {
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return v.AddSchema(kind, doc)
+}
+
+// Collector returns a SchemaCollector for kind, nil if it has no schema.
+func (v *Validator) Collector(kind string) (*SchemaCollector, error) {
+	v.mu.RLock()
+	schema, found := v.schemas[kind]
+	v.mu.RUnlock()
+	if !found {
+		if v.RejectUnknownKinds {
+			return nil, &UnknownKindError{Kind: kind}
+		}
+		return nil, nil
+	}
+	return NewSchemaCollector(schema), nil
+}
+
+// Validate checks data against the schema of kind, the paths of the
+// violations start with /data/data.
+func (v *Validator) Validate(kind string, data interface{}) error {
+	c, err := v.Collector(kind)
+	if err != nil || c == nil {
+		return err
+	}
+	if err := SortKeysE(data, c.AppendE, "/data/data"); err != nil {
+		return err
+	}
+	return c.Err(kind)
+}
+
+func (v *Validator) ValidateEnvelope(env *EnvelopeT) error {
+	return v.Validate(env.Data.Kind, env.Data.Data)
+}
@@ -0,0 +1,240 @@
+package c5
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidatorSuite struct {
+	suite.Suite
+	validator *Validator
+}
+
+const orderSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["customer", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"customer": {"type": "string", "pattern": "^c[0-9]*$", "minLength": 2, "maxLength": 8},
+		"status": {"enum": ["new", "paid", 7]},
+		"note": {"type": ["string", "null"]},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"maxItems": 2,
+			"items": {
+				"type": "object",
+				"required": ["sku", "count"],
+				"properties": {
+					"sku": {"type": "string"},
+					"count": {"type": "integer", "minimum": 1, "exclusiveMaximum": 100}
+				}
+			}
+		},
+		"tags": {"additionalProperties": {"type": "boolean"}},
+		"point": {"enum": [{"x": 1, "y": 2}, [1, 2]]},
+		"never": false
+	}
+}`
+
+func (s *ValidatorSuite) SetupTest() {
+	s.validator = NewValidator()
+	assert.NoError(s.T(), s.validator.AddSchema("order", []byte(orderSchema)))
+}
+
+func (s *ValidatorSuite) order() map[string]interface{} {
+	return map[string]interface{}{
+		"customer": "c1",
+		"status":   "paid",
+		"note":     nil,
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a", "count": 2},
+		},
+		"tags":  map[string]interface{}{"x": true},
+		"point": map[string]interface{}{"y": 2, "x": 1.0},
+	}
+}
+
+func (s *ValidatorSuite) violations(data map[string]interface{}) []SchemaViolation {
+	err := s.validator.Validate("order", data)
+	var verr *ValidationError
+	if !assert.True(s.T(), errors.As(err, &verr)) {
+		return nil
+	}
+	assert.Equal(s.T(), "order", verr.Kind)
+	return verr.Violations
+}
+
+func (s *ValidatorSuite) TestValid() {
+	assert.NoError(s.T(), s.validator.Validate("order", s.order()))
+	order := s.order()
+	order["status"] = 7.0
+	order["point"] = []interface{}{1, 2}
+	assert.NoError(s.T(), s.validator.Validate("order", order))
+	assert.NoError(s.T(), s.validator.Validate("unknown", order))
+}
+
+func (s *ValidatorSuite) TestViolations() {
+	for _, tc := range []struct {
+		change  func(map[string]interface{})
+		path    string
+		keyword string
+	}{
+		{func(o map[string]interface{}) { delete(o, "customer") }, "/data/data", "required"},
+		{func(o map[string]interface{}) { o["customer"] = 4 }, "/data/data/customer", "type"},
+		{func(o map[string]interface{}) { o["customer"] = "x1" }, "/data/data/customer", "pattern"},
+		{func(o map[string]interface{}) { o["customer"] = "c" }, "/data/data/customer", "minLength"},
+		{func(o map[string]interface{}) { o["customer"] = "c123456789" }, "/data/data/customer", "maxLength"},
+		{func(o map[string]interface{}) { o["status"] = "lost" }, "/data/data/status", "enum"},
+		{func(o map[string]interface{}) { o["note"] = 1 }, "/data/data/note", "type"},
+		{func(o map[string]interface{}) { o["items"] = []interface{}{} }, "/data/data/items", "minItems"},
+		{func(o map[string]interface{}) {
+			o["items"] = []interface{}{o["items"].([]interface{})[0], o["items"].([]interface{})[0], o["items"].([]interface{})[0]}
+		}, "/data/data/items", "maxItems"},
+		{func(o map[string]interface{}) { o["items"] = map[string]interface{}{} }, "/data/data/items", "type"},
+		{func(o map[string]interface{}) {
+			o["items"].([]interface{})[0].(map[string]interface{})["count"] = 1.5
+		}, "/data/data/items/0/count", "type"},
+		{func(o map[string]interface{}) {
+			o["items"].([]interface{})[0].(map[string]interface{})["count"] = 0
+		}, "/data/data/items/0/count", "minimum"},
+		{func(o map[string]interface{}) {
+			o["items"].([]interface{})[0].(map[string]interface{})["count"] = 100
+		}, "/data/data/items/0/count", "exclusiveMaximum"},
+		{func(o map[string]interface{}) {
+			delete(o["items"].([]interface{})[0].(map[string]interface{}), "sku")
+		}, "/data/data/items/0", "required"},
+		{func(o map[string]interface{}) { o["tags"] = map[string]interface{}{"y": "yes"} }, "/data/data/tags/y", "type"},
+		{func(o map[string]interface{}) { o["point"] = map[string]interface{}{"x": 2, "y": 1} }, "/data/data/point", "enum"},
+		{func(o map[string]interface{}) { o["extra"] = 1 }, "/data/data/extra", "additionalProperties"},
+		{func(o map[string]interface{}) { o["never"] = nil }, "/data/data/never", "false"},
+	} {
+		order := s.order()
+		tc.change(order)
+		violations := s.violations(order)
+		if assert.Len(s.T(), violations, 1, tc.keyword) {
+			assert.Equal(s.T(), tc.path, violations[0].Path)
+			assert.Equal(s.T(), tc.keyword, violations[0].Keyword)
+		}
+	}
+}
+
+func (s *ValidatorSuite) TestAllViolations() {
+	order := s.order()
+	order["customer"] = true
+	order["status"] = "lost"
+	violations := s.violations(order)
+	assert.Len(s.T(), violations, 2)
+	err := s.validator.Validate("order", order)
+	assert.Equal(s.T(), "payload kind 'order' is invalid:/data/data/customer: expected string, got boolean (and 1 more)", err.Error())
+}
+
+func (s *ValidatorSuite) TestStructData() {
+	o := order{Customer: "c1", Items: []item{{Sku: "a", Count: 0}}, Tags: []string{}}
+	err := s.validator.Validate("order", o)
+	var verr *ValidationError
+	assert.True(s.T(), errors.As(err, &verr))
+	assert.Len(s.T(), verr.Violations, 1)
+	assert.Equal(s.T(), "/data/data/items/0/count", verr.Violations[0].Path)
+}
+
+func (s *ValidatorSuite) TestInvalidSchema() {
+	for _, tc := range []struct {
+		doc     string
+		pointer string
+	}{
+		{`[]`, ""},
+		{`{"type": "text"}`, "/type"},
+		{`{"properties": {"a": {"pattern": "("}}}`, "/properties/a/pattern"},
+		{`{"items": {"minLength": -1}}`, "/items/minLength"},
+		{`{"maximum": "1"}`, "/maximum"},
+		{`{"required": [1]}`, "/required"},
+	} {
+		_, err := CompileSchema([]byte(tc.doc))
+		var serr *InvalidSchemaError
+		if assert.True(s.T(), errors.As(err, &serr), tc.doc) {
+			assert.Equal(s.T(), tc.pointer, serr.Pointer)
+		}
+	}
+}
+
+func (s *ValidatorSuite) TestRejectUnknownKinds() {
+	s.validator.RejectUnknownKinds = true
+	err := s.validator.Validate("unknown", map[string]interface{}{})
+	var kerr *UnknownKindError
+	assert.True(s.T(), errors.As(err, &kerr))
+}
+
+func (s *ValidatorSuite) TestAddSchemaFile() {
+	path := filepath.Join(s.T().TempDir(), "y.json")
+	assert.NoError(s.T(), os.WriteFile(path, []byte(`{"required": ["y"]}`), 0o600))
+	assert.NoError(s.T(), s.validator.AddSchemaFile("y", path))
+	assert.Error(s.T(), s.validator.Validate("y", map[string]interface{}{}))
+	assert.Error(s.T(), s.validator.AddSchemaFile("z", filepath.Join(s.T().TempDir(), "missing.json")))
+}
+
+func (s *ValidatorSuite) envelopeJson(data map[string]interface{}) []byte {
+	return []byte(*NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{},
+		Data:          PayloadT1{Kind: "order", Data: data},
+		TimeGenerator: mtimer,
+	}).AsJson())
+}
+
+func (s *ValidatorSuite) TestParse() {
+	data := s.order()
+	data["note"] = "fragile"
+	valid := s.envelopeJson(data)
+	data["customer"] = "x"
+	invalid := s.envelopeJson(data)
+
+	_, err := ParseSimpleEnvelope(valid, WithValidator(s.validator))
+	assert.NoError(s.T(), err)
+	_, err = ParseSimpleEnvelope(invalid)
+	assert.NoError(s.T(), err)
+
+	var verr *ValidationError
+	_, err = ParseSimpleEnvelope(invalid, WithValidator(s.validator))
+	assert.True(s.T(), errors.As(err, &verr))
+	_, err = ParseEnvelope[map[string]interface{}](invalid, WithValidator(s.validator))
+	assert.True(s.T(), errors.As(err, &verr))
+
+	registry := NewKindRegistry()
+	assert.NoError(s.T(), RegisterKind[map[string]interface{}](registry, "order"))
+	_, err = registry.Parse(invalid, WithValidator(s.validator))
+	assert.True(s.T(), errors.As(err, &verr))
+	_, err = registry.Decode(invalid, WithValidator(s.validator))
+	assert.True(s.T(), errors.As(err, &verr))
+	_, err = registry.Decode(valid, WithValidator(s.validator))
+	assert.NoError(s.T(), err)
+}
+
+func (s *ValidatorSuite) TestCollectWithinWalk() {
+	order := s.order()
+	order["status"] = "lost"
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{},
+		Data:          PayloadT1{Kind: "order", Data: order},
+		TimeGenerator: mtimer,
+	})
+	c, err := s.validator.Collector("order")
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), se.Collect(c))
+	assert.Equal(s.T(), []SchemaViolation{{
+		Path:    "/data/data/status",
+		Keyword: "enum",
+		Message: `"lost" is not one of the enum`,
+	}}, c.Violations())
+}
+
+func TestValidatorSuite(t *testing.T) {
+	suite.Run(t, new(ValidatorSuite))
+}
@@ -0,0 +1,217 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+func contentID(t int64, hash string) string {
+	return fmt.Sprintf("%v-%v", t, hash)
+}
+
+func dataHash(data interface{}, spec HashSpec) (string, error) {
+	hashC, err := NewHashCollectorSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	if err := SortKeysE(data, hashC.AppendE); err != nil {
+		return "", err
+	}
+	return hashC.Digest(), nil
+}
+
+// verifyID recomputes the id of the header env with g, the HashSpec is
+// the one of the digest in the id. Without g any generator whose ids look
+// like env.ID may have made it. Ids of a generator which is not
+// Verifiable are taken as they are.
+func verifyID(g IDGenerator, env *EnvelopeT, data interface{}) error {
+	generators := []IDGenerator{g}
+	if g == nil {
+		generators = idGeneratorsOf(env.ID)
+	}
+	spec := ParseDigest(idDigest(env.ID))
+	spec.Tagged = env.V == V_B
+	var hash string
+	var mismatch *IDMismatchError
+	for _, g := range generators {
+		if !g.Verifiable() {
+			return nil
+		}
+		if hash == "" {
+			var err error
+			if hash, err = contentHash(env, data, spec); err != nil {
+				return err
+			}
+		}
+		expected, err := g.NewID(IDInput{
+			T:      env.T,
+			Tr:     resolution(env.Tr),
+			Src:    env.Src,
+			Kind:   env.Data.Kind,
+			Digest: hash,
+			Spec:   spec,
+		})
+		if err != nil {
+			return err
+		}
+		if env.ID == expected {
+			return nil
+		}
+		if mismatch == nil {
+			mismatch = &IDMismatchError{ID: env.ID, Expected: expected}
+		}
+	}
+	return mismatch
+}
+
+// VerifyEnvelopeT recomputes the id of env and returns an
+// *IDMismatchError if it differs from env.ID. The id is taken for one of
+// the generators its shape tells unless WithIDGenerator says otherwise,
+// the other options do not apply.
+func VerifyEnvelopeT(env *EnvelopeT, opts ...ParseOption) error {
+	return verifyID(newParseOptions(opts).idGenerator, env, env.Data.Data)
+}
+
+// jsonData decodes /data/data of an envelope JSON. The numbers stay
+// literal, float64 would reformat integers like 1624140000000 to
+// 1.62414e+12 for the hash.
+func jsonData(b []byte) (interface{}, error) {
+	raw := struct {
+		Data struct {
+			Data interface{} `json:"data"`
+		} `json:"data"`
+	}{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw.Data.Data, nil
+}
+
+// ParseOption adds checks to the parsing of an envelope.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	idGenerator IDGenerator
+	validator   *Validator
+	freshness   *Freshness
+	replay      *ReplayGuard
+}
+
+func newParseOptions(opts []ParseOption) *parseOptions {
+	o := &parseOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithIDGenerator verifies the ids as g makes them. If not given, any
+// built-in generator whose ids look like the id may have made it. The ids
+// of a generator which is not Verifiable are not checked.
+func WithIDGenerator(g IDGenerator) ParseOption {
+	return func(o *parseOptions) {
+		o.idGenerator = g
+	}
+}
+
+// WithValidator rejects payloads which do not match the schema of their
+// kind with a ValidationError.
+func WithValidator(v *Validator) ParseOption {
+	return func(o *parseOptions) {
+		o.validator = v
+	}
+}
+
+// WithFreshness rejects envelopes which are expired or from the future
+// with an EnvelopeExpiredError or an EnvelopeFromFutureError.
+func WithFreshness(f *Freshness) ParseOption {
+	return func(o *parseOptions) {
+		o.freshness = f
+	}
+}
+
+// WithReplayGuard rejects envelopes whose id g has seen with a
+// ReplayedEnvelopeError. The id is remembered only once all other checks
+// passed.
+func WithReplayGuard(g *ReplayGuard) ParseOption {
+	return func(o *parseOptions) {
+		o.replay = g
+	}
+}
+
+// check runs the checks of o on the header of env and the decoded data
+// of its payload, the id is verified apart.
+func (o *parseOptions) check(env *EnvelopeT, data interface{}) error {
+	if o.freshness != nil {
+		if err := o.freshness.Check(env); err != nil {
+			return err
+		}
+	}
+	if o.validator != nil {
+		if err := o.validator.Validate(env.Data.Kind, data); err != nil {
+			return err
+		}
+	}
+	if o.replay != nil {
+		return o.replay.Check(env)
+	}
+	return nil
+}
+
+// ParseSimpleEnvelope decodes an envelope from its JSON form and verifies
+// that its id matches its content.
+func ParseSimpleEnvelope(b []byte, opts ...ParseOption) (*SimpleEnvelope, error) {
+	env, err := UnmarshalEnvelopeT(b)
+	if err != nil {
+		return nil, err
+	}
+	data, err := jsonData(b)
+	if err != nil {
+		return nil, err
+	}
+	o := newParseOptions(opts)
+	if err := verifyID(o.idGenerator, env, data); err != nil {
+		return nil, err
+	}
+	if err := o.check(env, data); err != nil {
+		return nil, err
+	}
+	spec := ParseDigest(idDigest(env.ID))
+	se, err := NewSimpleEnvelopeE(&SimpleEnvelopeProps{
+		ID:             env.ID,
+		HashAlgorithm:  spec.Algorithm,
+		DigestEncoding: spec.Encoding,
+		Src:            env.Src,
+		Dst:            env.Dst,
+		T:              env.T,
+		TimeResolution: resolution(env.Tr),
+		TTL:            int(env.TTL),
+		Via:            env.Via,
+		Data:           env.Data,
+		V:              env.V,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// without WithIDGenerator Verify tells the generator by the id too
+	se.simpleEnvelopeProps.IDGenerator = o.idGenerator
+	// exp is taken as is, it need not be a whole MaxAge after t, hashed
+	// in its order, it is hashed itself, and a ttl of 0 stays 0
+	se.simpleEnvelopeProps.TTL = int(env.TTL)
+	se.simpleEnvelopeProps.Exp = env.Exp
+	se.simpleEnvelopeProps.Hashed = env.Hashed
+	return se, nil
+}
+
+// Verify checks the id of the envelope against its content as its
+// IDGenerator makes it.
+func (s *SimpleEnvelope) Verify() error {
+	env, err := s.AsEnvelopeE()
+	if err != nil {
+		return err
+	}
+	return verifyID(s.simpleEnvelopeProps.IDGenerator, env, env.Data.Data)
+}
@@ -0,0 +1,103 @@
+package c5
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifySuite struct {
+	suite.Suite
+}
+
+func (s *VerifySuite) newEnvelopeJson(data map[string]interface{}) string {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: data,
+		},
+		Dst:           []string{},
+		TimeGenerator: mtimer,
+	})
+	return *se.AsJson()
+}
+
+func (s *VerifySuite) TestParseValid() {
+	js := s.newEnvelopeJson(map[string]interface{}{"name": "object", "date": "2021-05-20"})
+	se, err := ParseSimpleEnvelope([]byte(js))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), js, *se.AsJson())
+	assert.NoError(s.T(), se.Verify())
+}
+
+func (s *VerifySuite) TestParseLargeNumbers() {
+	js := s.newEnvelopeJson(map[string]interface{}{"y": 1624140000000, "z": 1.5})
+	_, err := ParseSimpleEnvelope([]byte(js))
+	assert.NoError(s.T(), err)
+}
+
+func (s *VerifySuite) TestParseFloatNumbers() {
+	// V_B hashes numbers as their JSON reads
+	envelope := func(data map[string]interface{}) string {
+		return *NewSimpleEnvelope(&SimpleEnvelopeProps{
+			Src:           "test case",
+			Dst:           []string{},
+			TimeGenerator: mtimer,
+			V:             V_B,
+			Data:          PayloadT1{Kind: "test", Data: data},
+		}).AsJson()
+	}
+	for _, f := range []float64{1000000, 123456789, 1.5e20, 1e21, 0.00001, 1e-7, -2.5e6} {
+		js := envelope(map[string]interface{}{"y": f, "a": []interface{}{f}})
+		_, err := ParseSimpleEnvelope([]byte(js))
+		assert.NoError(s.T(), err, f)
+	}
+	_, err := ParseSimpleEnvelope([]byte(envelope(map[string]interface{}{"y": float32(1000000)})))
+	assert.NoError(s.T(), err)
+}
+
+func (s *VerifySuite) TestFloatKeepsIDOfV_A() {
+	js := s.newEnvelopeJson(map[string]interface{}{"f": 1000000.0})
+	assert.Contains(s.T(), js, `"id":"1624140000000-J5RgjhFrEdCwvcqbQCjZc7C1h14PpQQ9qS5aw7NiCoJ8"`)
+}
+
+func (s *VerifySuite) TestParseTampered() {
+	js := s.newEnvelopeJson(map[string]interface{}{"name": "object", "date": "2021-05-20"})
+	_, err := ParseSimpleEnvelope([]byte(strings.Replace(js, "object", "objekt", 1)))
+	var merr *IDMismatchError
+	assert.True(s.T(), errors.As(err, &merr))
+	assert.Equal(s.T(), "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", merr.ID)
+	assert.NotEqual(s.T(), merr.ID, merr.Expected)
+}
+
+func (s *VerifySuite) TestParseTamperedTime() {
+	js := s.newEnvelopeJson(map[string]interface{}{"name": "object", "date": "2021-05-20"})
+	_, err := ParseSimpleEnvelope([]byte(strings.Replace(js, `"t":1624140000000`, `"t":1624140000001`, 1)))
+	var merr *IDMismatchError
+	assert.True(s.T(), errors.As(err, &merr))
+	assert.Equal(s.T(), "1624140000001-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp", merr.Expected)
+}
+
+func (s *VerifySuite) TestVerifyCallerID() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		ID:  "4711-wrong",
+		T:   4711,
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{"y": 4},
+		},
+	})
+	var merr *IDMismatchError
+	assert.True(s.T(), errors.As(se.Verify(), &merr))
+	assert.Equal(s.T(), "4711-wrong", merr.ID)
+	assert.True(s.T(), errors.As(VerifyEnvelopeT(se.AsEnvelope()), &merr))
+}
+
+func TestVerifySuite(t *testing.T) {
+	suite.Run(t, new(VerifySuite))
+}
@@ -0,0 +1,193 @@
+package c5
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WriteToSuite struct {
+	suite.Suite
+}
+
+func (s *WriteToSuite) props() *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{
+				"name":  "object",
+				"list":  []interface{}{1, "two", map[string]interface{}{"x": nil}},
+				"empty": map[string]interface{}{},
+			},
+		},
+		Dst:           []string{"a", "b"},
+		TimeGenerator: mtimer,
+	}
+}
+
+func (s *WriteToSuite) assertLikeAsJson(props *SimpleEnvelopeProps) {
+	var out bytes.Buffer
+	n, err := NewSimpleEnvelope(props).WriteTo(&out)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(out.Len()), n)
+	assert.Equal(s.T(), *NewSimpleEnvelope(props).AsJson(), out.String())
+}
+
+func (s *WriteToSuite) TestLikeAsJson() {
+	s.assertLikeAsJson(s.props())
+}
+
+func (s *WriteToSuite) TestLikeAsJsonIndent() {
+	props := s.props()
+	props.JsonProp = NewJsonProps(2, "")
+	s.assertLikeAsJson(props)
+}
+
+func (s *WriteToSuite) TestLikeAsJsonWithID() {
+	props := s.props()
+	props.ID = "given"
+	s.assertLikeAsJson(props)
+}
+
+func (s *WriteToSuite) TestLikeAsJsonWithMacAndV_B() {
+	props := s.props()
+	props.MacKey = []byte("secret")
+	props.MacKeyID = "k1"
+	props.V = V_B
+	s.assertLikeAsJson(props)
+}
+
+func (s *WriteToSuite) TestLikeAsJsonEmptyData() {
+	props := s.props()
+	props.Data = PayloadT1{Kind: "test", Data: map[string]interface{}{}}
+	s.assertLikeAsJson(props)
+}
+
+func (s *WriteToSuite) TestAfterAsJson() {
+	se := NewSimpleEnvelope(s.props())
+	json := *se.AsJson()
+	var out strings.Builder
+	n, err := se.WriteTo(&out)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(len(json)), n)
+	assert.Equal(s.T(), json, out.String())
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	return 0, f.err
+}
+
+func (s *WriteToSuite) TestWriterError() {
+	werr := errors.New("disk full")
+	_, err := NewSimpleEnvelope(s.props()).WriteTo(&failingWriter{err: werr})
+	assert.True(s.T(), errors.Is(err, werr))
+}
+
+func (s *WriteToSuite) TestWalkError() {
+	props := s.props()
+	props.Data = PayloadT1{Kind: "test", Data: map[string]interface{}{"ch": make(chan int)}}
+	var out bytes.Buffer
+	_, err := NewSimpleEnvelope(props).WriteTo(&out)
+	var uerr *UnmarshalableValueError
+	assert.True(s.T(), errors.As(err, &uerr))
+}
+
+func (s *WriteToSuite) TestJsonWriterCollector() {
+	data := s.props().Data
+	for _, props := range []*JsonProps{nil, NewJsonProps(4, "")} {
+		var fragments strings.Builder
+		fnC := NewJsonCollector(func(str string) {
+			fragments.WriteString(str)
+		}, props)
+		assert.NoError(s.T(), SortKeysE(data, fnC.AppendE))
+
+		var out bytes.Buffer
+		wC := NewJsonWriterCollector(&out, props)
+		assert.NoError(s.T(), SortKeysE(data, wC.AppendE))
+		assert.NoError(s.T(), wC.Flush())
+		assert.Equal(s.T(), fragments.String(), out.String())
+	}
+}
+
+func TestWriteToSuite(t *testing.T) {
+	suite.Run(t, new(WriteToSuite))
+}
+
+func benchProps(items int) *SimpleEnvelopeProps {
+	list := make([]interface{}, items)
+	for i := range list {
+		list[i] = map[string]interface{}{
+			"id":    i,
+			"name":  fmt.Sprintf("item-%d", i),
+			"tags":  []interface{}{"a", "b", "c"},
+			"value": float64(i) * 1.5,
+		}
+	}
+	return &SimpleEnvelopeProps{
+		Src: "bench",
+		Data: PayloadT1{
+			Kind: "bench",
+			Data: map[string]interface{}{"items": list},
+		},
+		Dst:           []string{},
+		TimeGenerator: mtimer,
+	}
+}
+
+func BenchmarkAsJson(b *testing.B) {
+	props := benchProps(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewSimpleEnvelope(props).AsJson()
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	props := benchProps(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewSimpleEnvelope(props).WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJsonCollectorOutputFN(b *testing.B) {
+	data := benchProps(10000).Data
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var parts []string
+		jsonC := NewJsonCollector(func(str string) {
+			parts = append(parts, str)
+		}, nil)
+		if err := SortKeysE(data, jsonC.AppendE); err != nil {
+			b.Fatal(err)
+		}
+		_ = strings.Join(parts, "")
+	}
+}
+
+func BenchmarkJsonCollectorWriter(b *testing.B) {
+	data := benchProps(10000).Data
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		jsonC := NewJsonWriterCollector(io.Discard, nil)
+		if err := SortKeysE(data, jsonC.AppendE); err != nil {
+			b.Fatal(err)
+		}
+		if err := jsonC.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 import { Payload } from './payload';
 
 export interface Envelope<T = unknown> {
-  readonly v: 'A'; // version never ever change, chuck norris rules this
+  readonly v: 'A' | 'B'; // A: plain data hash, B: type tagged data hash
   readonly id: string;
   readonly src: string;
   readonly dst: string[];
-  readonly t: number; //UTC Nanoseconds since 1970
+  readonly t: number; // UTC time since 1970 in units of tr
+  readonly exp?: number; // expiry time in units of tr
+  readonly hashed?: string[]; // the members the id covers, type tagged, only data.data if absent
+  readonly tr?: 'ms' | 'us' | 'ns'; // defaults to milliseconds
   readonly ttl: number; //Limit the hop count
+  readonly via?: string[]; // the hops which forwarded the envelope
   readonly data: Payload<T>;
 }